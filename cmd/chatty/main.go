@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"time"
 
 	"github.com/blackchip-org/chatty/irc"
 )
@@ -11,10 +12,24 @@ var s = irc.Server{}
 
 func init() {
 	flag.StringVar(&s.Addr, "address", irc.Addr, "address to listen on")
+	flag.StringVar(&s.Admin.Location, "admin-location", "", "location of the server sent in reply to ADMIN")
+	flag.StringVar(&s.Admin.Description, "admin-description", "", "description of the server sent in reply to ADMIN")
+	flag.StringVar(&s.Admin.Email, "admin-email", "", "contact email address sent in reply to ADMIN")
+	flag.StringVar(&s.CaseMapping, "casemapping", irc.DefaultCaseMapping, "compare names using ascii, rfc1459 or rfc7613")
 	flag.StringVar(&s.DataFile, "data", "chatty.data", "file that holds persistent data")
 	flag.BoolVar(&s.Debug, "debug", false, "enable debug")
+	flag.IntVar(&s.FloodBacklog, "flood-backlog", irc.DefaultFloodBacklog, "commands a client can have waiting before being disconnected")
+	flag.IntVar(&s.FloodBurst, "flood-burst", irc.DefaultFloodBurst, "commands a client can send at once before being limited")
+	flag.Float64Var(&s.FloodRate, "flood-rate", irc.DefaultFloodRate, "commands per second a client can send after a burst")
 	flag.BoolVar(&s.Insecure, "insecure", false, "use plaintext instead of tls")
+	flag.StringVar(&s.MotdFile, "motd", "", "file that holds the message of the day")
 	flag.StringVar(&s.Name, "name", irc.ServerName, "override the name of the server")
+	flag.StringVar(&s.Network, "network", "", "name of the network advertised to clients")
+	flag.DurationVar(&s.NickGrace, "nick-grace", irc.DefaultNickGrace, "time to log in before a registered nick is changed")
+	flag.DurationVar(&s.PingInterval, "ping-interval", 2*time.Minute, "send a PING to clients that are silent for this long")
+	flag.DurationVar(&s.PingTimeout, "ping-timeout", time.Minute, "disconnect clients that do not answer a PING within this time")
+	flag.BoolVar(&s.RequireSASL, "require-sasl", false, "only allow clients that log in with SASL instead of a connection password")
+	flag.IntVar(&s.SendQ, "sendq", irc.DefaultSendQ, "bytes that can be waiting to be sent to a client")
 }
 
 func main() {
@@ -1,6 +1,7 @@
 package fntest
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 	"time"
@@ -68,3 +69,122 @@ func TestRegistrationDeadline(t *testing.T) {
 		t.Fatalf("unexpected error: %v", c3.Err())
 	}
 }
+
+func TestExcessFlood(t *testing.T) {
+	if tester.RealServer {
+		t.Skip("skipping test on real server")
+	}
+	s, c := tester.NewServerConfig(t, func(s *irc.Server) {
+		s.FloodBacklog = 5
+		s.FloodBurst = 5
+		s.FloodRate = 0.1
+	})
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Joker", "joker 0 * :The Joker").Join("#gotham")
+	for i := 0; i < 20; i++ {
+		c2.Send("PRIVMSG #gotham :HA")
+	}
+	have := c.WaitFor(irc.QuitCmd).Encode()
+	want := ":Joker!~joker@localhost QUIT :Excess Flood"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestFloodDelayed(t *testing.T) {
+	if tester.RealServer {
+		t.Skip("skipping test on real server")
+	}
+	s, c := tester.NewServerConfig(t, func(s *irc.Server) {
+		s.FloodBurst = 2
+		s.FloodRate = 10
+	})
+	defer s.Quit()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne")
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		c.Send(fmt.Sprintf("JOIN #gotham%v", i))
+	}
+	for i := 0; i < 10; i++ {
+		have := c.WaitFor(irc.JoinCmd).Encode()
+		want := fmt.Sprintf(":Batman!~batman@localhost JOIN :#gotham%v", i)
+		if want != have {
+			t.Fatalf("\n want: %v \n have: %v", want, have)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 700*time.Millisecond {
+		t.Fatalf("burst was not delayed: %v", elapsed)
+	}
+	if c.Err() != nil {
+		t.Fatalf("unexpected error: %v", c.Err())
+	}
+}
+
+func TestFloodDelayedBeforeRegistration(t *testing.T) {
+	if tester.RealServer {
+		t.Skip("skipping test on real server")
+	}
+	s, c := tester.NewServerConfig(t, func(s *irc.Server) {
+		s.FloodBurst = 2
+		s.FloodRate = 10
+	})
+	defer s.Quit()
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		c.Send("CAP LS")
+	}
+	for i := 0; i < 10; i++ {
+		c.WaitFor(irc.CapCmd)
+	}
+	if elapsed := time.Since(start); elapsed < 700*time.Millisecond {
+		t.Fatalf("burst was not delayed: %v", elapsed)
+	}
+	if c.Err() != nil {
+		t.Fatalf("unexpected error: %v", c.Err())
+	}
+}
+
+func TestMessageTagsNotTooLong(t *testing.T) {
+	if tester.RealServer {
+		t.Skip("skipping test on real server")
+	}
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.Login("Joker", "joker 0 * :Jack Nicholson")
+	c.Send("@+joke=" + strings.Repeat("X", irc.MessageMaxLen*2) + " PING :HA")
+	have := c.Recv()
+	want := ":irc.localhost PONG irc.localhost :HA"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestMessageTagsTooLong(t *testing.T) {
+	if tester.RealServer {
+		t.Skip("skipping test on real server")
+	}
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.Login("Joker", "joker 0 * :Jack Nicholson")
+	for _, n := range []int{irc.TagsMaxLen, irc.TagsMaxLen * 2} {
+		c.Send("@+joke=" + strings.Repeat("X", n) + " PING :HA")
+		have := c.Recv()
+		want := ":irc.localhost 417 Joker :Input line was too long"
+		if want != have {
+			t.Fatalf("\n want: %v \n have: %v", want, have)
+		}
+	}
+	c.Send("PING :HA")
+	have := c.Recv()
+	want := ":irc.localhost PONG irc.localhost :HA"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
@@ -0,0 +1,262 @@
+package fntest
+
+import (
+	"encoding/base64"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blackchip-org/chatty/internal/security"
+	"github.com/blackchip-org/chatty/internal/tester"
+	"github.com/blackchip-org/chatty/irc"
+	"github.com/boltdb/bolt"
+)
+
+// Accounts are kept in a new data file for each test so that names can be
+// registered again.
+func newAccountServer(t *testing.T, config func(*irc.Server)) (*tester.Server, *tester.Client) {
+	return tester.NewServerConfig(t, func(s *irc.Server) {
+		s.DataFile = filepath.Join(t.TempDir(), "accounts.db")
+		if config != nil {
+			config(s)
+		}
+	})
+}
+
+func TestRegisterBeforeConnect(t *testing.T) {
+	s, c := newAccountServer(t, nil)
+	defer s.Quit()
+
+	c.Send("CAP LS 302")
+	c.WaitFor(irc.CapCmd)
+	c.Send("NICK Batman")
+	c.Send("REGISTER * * alfred123")
+	have := c.Recv()
+	want := ":irc.localhost REGISTER SUCCESS Batman :Account created"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	have = c.Recv()
+	want = ":irc.localhost 900 Batman Batman!~*@irc.localhost Batman :You are now logged in as Batman"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	c.Send("USER batman 0 * :Bruce Wayne")
+	c.Send("CAP END")
+	c.WaitFor(irc.RplWelcome)
+	if c.Err() != nil {
+		t.Fatalf("unexpected error: %v", c.Err())
+	}
+}
+
+// putConfig stores values in the config bucket of the data file before the
+// server starts.
+func putConfig(t *testing.T, dataFile string, values map[string][]byte) {
+	db, err := bolt.Open(dataFile, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Update(func(tx *bolt.Tx) error {
+		config, err := tx.CreateBucketIfNotExists(irc.BucketConfig)
+		if err != nil {
+			return err
+		}
+		for key, value := range values {
+			if err := config.Put([]byte(key), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setServerPass(t *testing.T, dataFile string, plaintext string) {
+	salt, err := security.Salt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	putConfig(t, dataFile, map[string][]byte{
+		string(irc.ConfigPass): security.EncodePassword([]byte(plaintext), salt),
+		string(irc.ConfigSalt): salt,
+	})
+}
+
+func TestRegisterBeforeConnectRefused(t *testing.T) {
+	configs := map[string]func(*irc.Server){
+		"require sasl": func(s *irc.Server) {
+			s.RequireSASL = true
+		},
+		"server password": func(s *irc.Server) {
+			setServerPass(t, s.DataFile, "swordfish")
+		},
+	}
+	for name, config := range configs {
+		t.Run(name, func(t *testing.T) {
+			s, c := newAccountServer(t, config)
+			defer s.Quit()
+
+			c.Send("CAP LS 302")
+			have := c.Recv()
+			want := "draft/account-registration=custom-account-name"
+			if !strings.Contains(have, want) {
+				t.Fatalf("\n want: %v \n have: %v", want, have)
+			}
+			c.Send("NICK Batman")
+			c.Send("REGISTER * * alfred123")
+			have = c.Recv()
+			want = ":irc.localhost FAIL REGISTER COMPLETE_CONNECTION_REQUIRED * :Complete the connection before registering an account"
+			if want != have {
+				t.Fatalf("\n want: %v \n have: %v", want, have)
+			}
+		})
+	}
+}
+
+func TestRegisterExists(t *testing.T) {
+	s, c := newAccountServer(t, nil)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.LoginDefault()
+	c.Send("REGISTER * * alfred123")
+	c.WaitFor("REGISTER")
+	c.Send("QUIT")
+
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+	c2.Send("REGISTER batman * dickgrayson")
+	have := c2.Recv()
+	want := ":irc.localhost FAIL REGISTER ACCOUNT_EXISTS batman :Account already exists"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestRegisterFail(t *testing.T) {
+	s, c := newAccountServer(t, nil)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.LoginDefault()
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"REGISTER Robin * dickgrayson", ":irc.localhost FAIL REGISTER BAD_ACCOUNT_NAME Robin :Account name is not valid or in use"},
+		{"REGISTER #gotham * dickgrayson", ":irc.localhost FAIL REGISTER BAD_ACCOUNT_NAME #gotham :Account name is not valid or in use"},
+		{"REGISTER * * alfred", ":irc.localhost FAIL REGISTER WEAK_PASSWORD Batman :Password must be at least 8 characters"},
+		{"REGISTER * *", ":irc.localhost 461 Batman REGISTER :Not enough parameters"},
+	}
+	for _, test := range tests {
+		c.Send(test.line)
+		have := c.Recv()
+		if test.want != have {
+			t.Fatalf("\n want: %v \n have: %v", test.want, have)
+		}
+	}
+}
+
+func TestSaslPlain(t *testing.T) {
+	s, c := newAccountServer(t, nil)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.LoginDefault()
+	c.Send("REGISTER * * alfred123")
+	c.WaitFor("REGISTER")
+	c.Send("QUIT")
+
+	c2.Send("CAP REQ :sasl")
+	c2.WaitFor(irc.CapCmd)
+	c2.Send("AUTHENTICATE PLAIN")
+	c2.WaitFor(irc.AuthenticateCmd)
+	c2.Send("AUTHENTICATE " + base64.StdEncoding.EncodeToString([]byte("\x00batman\x00alfred123")))
+	have := c2.Recv()
+	want := ":irc.localhost 900 * *!~*@irc.localhost Batman :You are now logged in as Batman"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	have = c2.Recv()
+	want = ":irc.localhost 903 * :SASL authentication successful"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	c2.Send("AUTHENTICATE PLAIN")
+	have = c2.Recv()
+	want = ":irc.localhost 907 * :You have already authenticated using SASL"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestWhoisAccount(t *testing.T) {
+	s, c := newAccountServer(t, nil)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.LoginDefault()
+	c.Send("REGISTER * * alfred123")
+	c.WaitFor("REGISTER")
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+	c2.Send("WHOIS Batman")
+	have := c2.WaitFor(irc.RplWhoisAccount).Encode()
+	want := ":irc.localhost 330 Robin Batman Batman :is logged in as"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestAccountTag(t *testing.T) {
+	s, c := newAccountServer(t, nil)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.LoginDefault()
+	c.Send("REGISTER * * alfred123")
+	c.WaitFor("REGISTER")
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+	c2.Send("CAP REQ :account-tag")
+	c2.WaitFor(irc.CapCmd)
+
+	c.Send("PRIVMSG Robin :To the Batmobile!")
+	have := c2.Recv()
+	want := "@account=Batman :Batman!~Batman@localhost PRIVMSG Robin :To the Batmobile!"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	c2.Send("PRIVMSG Batman :Holy guacamole!")
+	have = c.WaitFor(irc.PrivMsgCmd).Encode()
+	want = ":Robin!~robin@localhost PRIVMSG Batman :Holy guacamole!"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestNickGrace(t *testing.T) {
+	s, c := newAccountServer(t, func(s *irc.Server) {
+		s.NickGrace = 100 * time.Millisecond
+	})
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.LoginDefault()
+	c.Send("REGISTER Robin * dickgrayson")
+	c.WaitFor("REGISTER")
+
+	c2.Login("Robin", "joker 0 * :The Joker")
+	have := c2.WaitFor(irc.NoticeCmd).Encode()
+	want := ":irc.localhost NOTICE Robin :This nick is registered."
+	if !strings.HasPrefix(have, want) {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	have = c2.WaitFor(irc.NickCmd).Encode()
+	want = ":Robin!~joker@localhost NICK :Guest"
+	if !strings.HasPrefix(have, want) {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
@@ -0,0 +1,123 @@
+package fntest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/blackchip-org/chatty/internal/tester"
+	"github.com/blackchip-org/chatty/irc"
+)
+
+func TestAway(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.LoginDefault()
+	c.Send("AWAY :On patrol")
+	have := c.Recv()
+	want := ":irc.localhost 306 Batman :You have been marked as being away"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	c.Send("AWAY")
+	have = c.Recv()
+	want = ":irc.localhost 305 Batman :You are no longer marked as being away"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestAwayPrivMsg(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne")
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+	c.Send("AWAY :On patrol")
+	c.WaitFor(irc.RplNowAway)
+
+	c2.Send("PRIVMSG Batman :Holy away message!")
+	have := c2.Recv()
+	want := ":irc.localhost 301 Robin Batman :On patrol"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestAwayWhois(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne")
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+	c.Send("AWAY :On patrol")
+	c.WaitFor(irc.RplNowAway)
+
+	c2.Send("WHOIS Batman")
+	have := c2.WaitFor(irc.RplAway).Encode()
+	want := ":irc.localhost 301 Robin Batman :On patrol"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestAwayWho(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c.Send("AWAY :On patrol")
+	c.WaitFor(irc.RplNowAway)
+	c.Send("WHO #gotham")
+
+	have := AnyOf(c.Recv(), "irc.localhost", DockerIp)
+	have = strings.Replace(have, "000A ", "", 1)
+	want := ":X 352 Batman #gotham ~batman X X Batman G@ :0 Bruce Wayne"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestAwayNotify(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+	c2.Send("CAP REQ :away-notify")
+	have := c2.Recv()
+	want := ":irc.localhost CAP Robin ACK :away-notify"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	c2.Join("#gotham")
+
+	c.Send("AWAY :On patrol")
+	have = c2.WaitFor(irc.AwayCmd).Encode()
+	want = ":Batman!~batman@localhost AWAY :On patrol"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	c.Send("AWAY")
+	have = c2.WaitFor(irc.AwayCmd).Encode()
+	want = ":Batman!~batman@localhost AWAY"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestCapReqUnsupported(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.LoginDefault()
+	c.Send("CAP REQ :away-notify batcomputer")
+	have := c.Recv()
+	want := ":irc.localhost CAP Batman NAK :away-notify batcomputer"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
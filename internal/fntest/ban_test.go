@@ -0,0 +1,154 @@
+package fntest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/blackchip-org/chatty/internal/tester"
+	"github.com/blackchip-org/chatty/irc"
+)
+
+func TestBanJoin(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Joker", "joker 0 * :The Joker")
+
+	c.Send("MODE #gotham +b Joker")
+	have := c.Recv()
+	want := ":Batman!~batman@localhost MODE #gotham +b Joker!*@*"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+
+	c2.Send("JOIN #gotham")
+	have = c2.Recv()
+	want = ":irc.localhost 474 Joker #gotham :Cannot join channel (+b)"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestBanSpeak(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Joker", "joker 0 * :The Joker").Join("#gotham")
+
+	c.Send("MODE #gotham +b *!~joker@*")
+	c2.WaitFor(irc.ModeCmd)
+	c2.Send("PRIVMSG #gotham :Why so serious?")
+	have := c2.Recv()
+	want := ":irc.localhost 404 Joker #gotham :Cannot send to channel"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestBanRemove(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Joker", "joker 0 * :The Joker")
+
+	c.Send("MODE #gotham +b Joker")
+	c.WaitFor(irc.ModeCmd)
+	c.Send("MODE #gotham -b Joker")
+	have := c.Recv()
+	want := ":Batman!~batman@localhost MODE #gotham -b Joker!*@*"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+
+	c2.Join("#gotham")
+	if c2.Err() != nil {
+		t.Fatalf("unexpected error: %v", c2.Err())
+	}
+}
+
+func TestBanList(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c.Send("MODE #gotham +bb Joker Riddler")
+	c.WaitFor(irc.ModeCmd)
+
+	c.Send("MODE #gotham +b")
+	wants := []string{
+		":irc.localhost 367 Batman #gotham :Joker!*@*",
+		":irc.localhost 367 Batman #gotham :Riddler!*@*",
+		":irc.localhost 368 Batman #gotham :End of Channel Ban List",
+	}
+	for _, want := range wants {
+		have := c.Recv()
+		if want != have {
+			t.Fatalf("\n want: %v \n have: %v", want, have)
+		}
+	}
+}
+
+func TestBanException(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+
+	c.Send("MODE #gotham +be *!*@* Robin")
+	c.WaitFor(irc.ModeCmd)
+	c2.Join("#gotham")
+	if c2.Err() != nil {
+		t.Fatalf("unexpected error: %v", c2.Err())
+	}
+
+	c.Drain()
+	c.Send("MODE #gotham e")
+	have := c.Recv()
+	want := ":irc.localhost 348 Batman #gotham :Robin!*@*"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	have = c.Recv()
+	want = ":irc.localhost 349 Batman #gotham :End of Channel Exception List"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestBanNotOper(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Joker", "joker 0 * :The Joker").Join("#gotham")
+
+	c2.Send("MODE #gotham +b Batman")
+	c2.WaitFor(irc.ErrChanOpPrivsNeeded)
+	if c2.Err() != nil {
+		t.Fatalf("unexpected error: %v", c2.Err())
+	}
+}
+
+func TestBanMaskTruncated(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+
+	mask := "Joker!*@" + strings.Repeat("a", 200)
+	c.Send("MODE #gotham +b " + mask)
+	have := c.Recv()
+	want := ":Batman!~batman@localhost MODE #gotham +b " + mask[:irc.MaskMaxLen]
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
@@ -0,0 +1,136 @@
+package fntest
+
+import (
+	"testing"
+
+	"github.com/blackchip-org/chatty/internal/tester"
+	"github.com/blackchip-org/chatty/irc"
+)
+
+func TestCapLs302(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.Send("CAP LS 302")
+	have := c.Recv()
+	want := ":irc.localhost CAP * LS :account-tag away-notify cap-notify draft/account-registration=before-connect,custom-account-name message-tags sasl=PLAIN"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	c.Send("NICK Batman")
+	c.Send("USER batman 0 * :Bruce Wayne")
+	c.Send("CAP LIST")
+	have = c.Recv()
+	want = ":irc.localhost CAP Batman LIST :cap-notify"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	c.Send("CAP END")
+	have = c.WaitFor(irc.RplWelcome).Cmd
+	if have != irc.RplWelcome {
+		t.Fatalf("\n want: %v \n have: %v", irc.RplWelcome, have)
+	}
+}
+
+func TestCapReqBeforeRegistration(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.Send("CAP REQ :away-notify")
+	have := c.Recv()
+	want := ":irc.localhost CAP * ACK :away-notify"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	c.Send("NICK Batman")
+	c.Send("USER batman 0 * :Bruce Wayne")
+	c.Send("CAP REQ :batcomputer")
+	have = c.Recv()
+	want = ":irc.localhost CAP Batman NAK :batcomputer"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	c.Send("CAP END")
+	c.WaitFor(irc.RplWelcome)
+	c.Drain()
+	c.Send("CAP LIST")
+	have = c.Recv()
+	want = ":irc.localhost CAP Batman LIST :away-notify"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestCapInvalid(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.LoginDefault()
+	c.Send("CAP FOO")
+	have := c.Recv()
+	want := ":irc.localhost 410 Batman FOO :Invalid CAP command"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestCapNotify(t *testing.T) {
+	if tester.RealServer {
+		t.Skip("skipping test on real server")
+	}
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Send("CAP LS 302")
+	c.Send("NICK Batman")
+	c.Send("USER batman 0 * :Bruce Wayne")
+	c.Send("CAP END")
+	c.WaitFor(irc.RplWelcome)
+	c.Drain()
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+
+	s.Actual.SetCap("batcomputer", "v2")
+	have := c.Recv()
+	want := ":irc.localhost CAP Batman NEW :batcomputer=v2"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	c.Send("CAP REQ batcomputer")
+	c.WaitFor(irc.CapCmd)
+
+	s.Actual.DelCap("batcomputer")
+	have = c.Recv()
+	want = ":irc.localhost CAP Batman DEL :batcomputer"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	c.Send("CAP LIST")
+	have = c.Recv()
+	want = ":irc.localhost CAP Batman LIST :cap-notify"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+
+	// Clients that did not enable cap-notify are not told
+	c2.Send("PING :HA")
+	have = c2.Recv()
+	want = ":irc.localhost PONG irc.localhost :HA"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestCapNotifyLocked(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.Send("CAP LS 302")
+	c.Recv()
+	c.Send("CAP REQ -cap-notify")
+	have := c.Recv()
+	want := ":irc.localhost CAP * NAK :-cap-notify"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
@@ -0,0 +1,65 @@
+package fntest
+
+import (
+	"testing"
+
+	"github.com/blackchip-org/chatty/internal/tester"
+	"github.com/blackchip-org/chatty/irc"
+)
+
+func TestCaseMappingNick(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne")
+	c2.Send("NICK BATMAN")
+	have := c2.Recv()
+	want := ":irc.localhost 433 * BATMAN :Nickname is already in use"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+	c2.Send("PRIVMSG batman :Holy capital letters!")
+	have = c.Recv()
+	want = ":Robin!~robin@localhost PRIVMSG Batman :Holy capital letters!"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestCaseMappingChannel(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#Gotham")
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+	c2.Send("JOIN #gotham")
+	have := c2.Recv()
+	want := ":Robin!~robin@localhost JOIN :#Gotham"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+
+	have = AnyOf(c2.WaitFor(irc.RplNameReply).Encode(), "@Batman", "Robin")
+	want = ":irc.localhost 353 X = #Gotham :X X"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestCaseMappingRFC1459(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("[Batman]", "batman 0 * :Bruce Wayne")
+	c2.Send("NICK {batman}")
+	have := c2.Recv()
+	want := ":irc.localhost 433 * {batman} :Nickname is already in use"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
@@ -601,3 +601,18 @@ func TestModeVoiceNoModeChange(t *testing.T) {
 		t.Errorf("\n want: no error \n have: %v", c.Err())
 	}
 }
+
+// ===== Limits
+
+func TestModeMaxParams(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c.Send("MODE #gotham +bbbb Joker Riddler Penguin Bane")
+	have := c.Recv()
+	want := ":Batman!~batman@localhost MODE #gotham +bbb Joker!*@* Riddler!*@* Penguin!*@*"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
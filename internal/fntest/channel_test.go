@@ -204,3 +204,43 @@ func TestWho(t *testing.T) {
 		t.Fatalf("\n want: %v \n have: %v", want, have)
 	}
 }
+
+func TestJoinMultiple(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.LoginDefault()
+	c.Send("JOIN #gotham,#batcave")
+	c.WaitFor(irc.RplEndOfNames)
+	have := c.WaitFor(irc.RplEndOfNames).Encode()
+	want := ":irc.localhost 366 Batman #batcave :End of NAMES list."
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestJoinNoSuchChannel(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.LoginDefault()
+	c.Send("JOIN gotham")
+	have := c.Recv()
+	want := ":irc.localhost 403 Batman gotham :No such channel"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestJoinBadChanMask(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.LoginDefault()
+	c.Send("JOIN #gotham\x07city")
+	have := c.Recv()
+	want := ":irc.localhost 476 Batman #gotham\x07city :Bad Channel Mask"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
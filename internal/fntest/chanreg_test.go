@@ -0,0 +1,233 @@
+package fntest
+
+import (
+	"encoding/base64"
+	"path/filepath"
+	"testing"
+
+	"github.com/blackchip-org/chatty/internal/tester"
+	"github.com/blackchip-org/chatty/irc"
+)
+
+// login registers the client with an account and logs in to it. Accounts
+// can only be created once for each data file.
+func login(c *tester.Client, nick string, user string, password string, create bool) {
+	c.Login(nick, user)
+	if create {
+		c.Send("REGISTER * * " + password)
+		c.WaitFor(irc.RplLoggedIn)
+		return
+	}
+	c.Send("CAP REQ :sasl")
+	c.WaitFor(irc.CapCmd)
+	c.Send("AUTHENTICATE PLAIN")
+	c.WaitFor(irc.AuthenticateCmd)
+	c.Send("AUTHENTICATE " + base64.StdEncoding.EncodeToString([]byte("\x00"+nick+"\x00"+password)))
+	c.WaitFor(irc.RplSaslSuccess)
+}
+
+func TestChanRegister(t *testing.T) {
+	dataFile := filepath.Join(t.TempDir(), "chans.db")
+	config := func(s *irc.Server) {
+		s.DataFile = dataFile
+	}
+
+	s, c := tester.NewServerConfig(t, config)
+	login(c, "Batman", "batman 0 * :Bruce Wayne", "alfred123", true)
+	c.Join("#gotham")
+	c.Send("TOPIC #gotham :Protect the city")
+	c.WaitFor(irc.TopicCmd)
+	c.Send("MODE #gotham +kb batcave Joker")
+	c.WaitFor(irc.ModeCmd)
+	c.Send("REGCHAN #gotham")
+	have := c.Recv()
+	want := ":irc.localhost REGCHAN SUCCESS #gotham :Channel registered"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	s.Quit()
+
+	s, c = tester.NewServerConfig(t, config)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+	c2.Send("JOIN #gotham")
+	have = c2.Recv()
+	want = ":irc.localhost 475 Robin #gotham :Cannot join channel (+k)"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	c2.Send("JOIN #gotham batcave")
+	have = c2.WaitFor(irc.RplTopic).Encode()
+	want = ":irc.localhost 332 Robin #gotham :Protect the city"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	have = c2.WaitFor(irc.RplNameReply).Encode()
+	want = ":irc.localhost 353 Robin = #gotham :Robin"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+
+	login(c, "Batman", "batman 0 * :Bruce Wayne", "alfred123", false)
+	c.Send("JOIN #gotham batcave")
+	have = c.WaitFor(irc.RplNameReply).Encode()
+	want = ":irc.localhost 353 Batman = #gotham :@Batman Robin"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	c.Send("MODE #gotham +b")
+	have = c.WaitFor(irc.RplBanList).Encode()
+	want = ":irc.localhost 367 Batman #gotham :Joker!*@*"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestChanAccess(t *testing.T) {
+	s, c := newAccountServer(t, nil)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	login(c, "Batman", "batman 0 * :Bruce Wayne", "alfred123", true)
+	login(c2, "Robin", "robin 0 * :Boy Wonder", "dickgrayson", true)
+	c.Join("#gotham")
+	c.Send("REGCHAN #gotham")
+	c.WaitFor(irc.RegChanCmd)
+	c.Send("ACCESS #gotham ADD robin v")
+	have := c.Recv()
+	want := ":irc.localhost ACCESS SUCCESS #gotham :Access list updated"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+
+	c2.Send("JOIN #gotham")
+	have = c2.WaitFor(irc.RplNameReply).Encode()
+	want = ":irc.localhost 353 Robin = #gotham :+Robin @Batman"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+
+	c.Drain()
+	c.Send("ACCESS #gotham")
+	wants := []string{
+		":irc.localhost ACCESS #gotham LIST robin :v",
+		":irc.localhost ACCESS #gotham :END",
+	}
+	for _, want := range wants {
+		have := c.Recv()
+		if want != have {
+			t.Fatalf("\n want: %v \n have: %v", want, have)
+		}
+	}
+
+	c2.Drain()
+	c2.Send("ACCESS #gotham ADD robin o")
+	have = c2.Recv()
+	want = ":irc.localhost FAIL ACCESS NOT_FOUNDER #gotham :You are not the channel founder"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	c.Send("ACCESS #gotham ADD joker o")
+	have = c.Recv()
+	want = ":irc.localhost FAIL ACCESS NO_SUCH_ACCOUNT #gotham joker :No such account"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestChanAccessOnLogin(t *testing.T) {
+	s, c := newAccountServer(t, nil)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	login(c, "Batman", "batman 0 * :Bruce Wayne", "alfred123", true)
+	c.Join("#gotham")
+	c.Send("REGCHAN #gotham")
+	c.WaitFor(irc.RegChanCmd)
+
+	// Joins before creating an account
+	c2.Login("Robin", "robin 0 * :Boy Wonder").Join("#gotham")
+	c.WaitFor(irc.JoinCmd)
+	c2.Send("REGISTER * * dickgrayson")
+	c2.WaitFor(irc.RplLoggedIn)
+	c.Send("ACCESS #gotham ADD robin o")
+	c.WaitFor(irc.AccessCmd)
+	c2.Send("PART #gotham")
+	c.WaitFor(irc.PartCmd)
+
+	// Logs in after joining
+	c3 := s.NewClient()
+	c3.Login("Nightwing", "nightwing 0 * :Dick Grayson").Join("#gotham")
+	c.WaitFor(irc.JoinCmd)
+	c3.Send("CAP REQ :sasl")
+	c3.WaitFor(irc.CapCmd)
+	c3.Send("AUTHENTICATE PLAIN")
+	c3.WaitFor(irc.AuthenticateCmd)
+	c3.Send("AUTHENTICATE " + base64.StdEncoding.EncodeToString([]byte("\x00Robin\x00dickgrayson")))
+	have := c.WaitFor(irc.ModeCmd).Encode()
+	want := ":irc.localhost MODE #gotham +o :Nightwing"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	have = c3.WaitFor(irc.ModeCmd).Encode()
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestChanRegisterFail(t *testing.T) {
+	s, c := newAccountServer(t, nil)
+	defer s.Quit()
+
+	c.LoginDefault().Join("#gotham")
+	c.Send("REGCHAN #gotham")
+	have := c.Recv()
+	want := ":irc.localhost FAIL REGCHAN ACCOUNT_REQUIRED #gotham :You must be logged in"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	c.Send("DROPCHAN #gotham")
+	have = c.Recv()
+	want = ":irc.localhost FAIL DROPCHAN NOT_REGISTERED #gotham :Channel is not registered"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestChanDrop(t *testing.T) {
+	s, c := newAccountServer(t, nil)
+	defer s.Quit()
+
+	login(c, "Batman", "batman 0 * :Bruce Wayne", "alfred123", true)
+	c.Join("#gotham")
+	c.Send("REGCHAN #gotham")
+	c.WaitFor(irc.RegChanCmd)
+	c.Send("DROPCHAN #gotham")
+	have := c.Recv()
+	want := ":irc.localhost DROPCHAN SUCCESS #gotham :Channel registration dropped"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestChanEmptyRemoved(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.LoginDefault().Join("#gotham")
+	c.Send("TOPIC #gotham :Protect the city")
+	c.WaitFor(irc.TopicCmd)
+	c.Send("PART #gotham")
+	c.WaitFor(irc.PartCmd)
+
+	c2.Login("Joker", "joker 0 * :The Joker").Join("#gotham")
+	c2.Send("TOPIC #gotham")
+	have := c2.Recv()
+	want := ":irc.localhost 331 Joker #gotham :No topic is set."
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
@@ -0,0 +1,145 @@
+package fntest
+
+import (
+	"testing"
+
+	"github.com/blackchip-org/chatty/internal/tester"
+	"github.com/blackchip-org/chatty/irc"
+)
+
+func TestInvite(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+
+	c.Send("INVITE Robin #gotham")
+	have := c.Recv()
+	want := ":irc.localhost 341 Batman Robin :#gotham"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	have = c2.Recv()
+	want = ":Batman!~batman@localhost INVITE Robin :#gotham"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestInviteOnly(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+
+	c.Send("MODE #gotham +i")
+	c.WaitFor(irc.ModeCmd)
+
+	c2.Send("JOIN #gotham")
+	have := c2.Recv()
+	want := ":irc.localhost 473 Robin #gotham :Cannot join channel (+i)"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+
+	c.Send("INVITE Robin #gotham")
+	c2.WaitFor(irc.InviteCmd)
+	c2.Join("#gotham")
+	if c2.Err() != nil {
+		t.Fatalf("unexpected error: %v", c2.Err())
+	}
+}
+
+func TestInviteConsumed(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+
+	c.Send("MODE #gotham +i")
+	c.WaitFor(irc.ModeCmd)
+	c.Send("INVITE Robin #gotham")
+	c2.WaitFor(irc.InviteCmd)
+	c2.Join("#gotham")
+	c2.Send("PART #gotham")
+	c2.WaitFor(irc.PartCmd)
+
+	c2.Send("JOIN #gotham")
+	c2.WaitFor(irc.ErrInviteOnlyChan)
+	if c2.Err() != nil {
+		t.Fatalf("unexpected error: %v", c2.Err())
+	}
+}
+
+func TestInviteNotOper(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+	c3 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Robin", "robin 0 * :Boy Wonder").Join("#gotham")
+	c3.Login("Joker", "joker 0 * :The Joker")
+
+	c.Send("MODE #gotham +i")
+	c2.WaitFor(irc.ModeCmd)
+
+	c2.Send("INVITE Joker #gotham")
+	have := c2.Recv()
+	want := ":irc.localhost 482 Robin #gotham :You're not channel operator"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestInviteAlreadyOnChannel(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Robin", "robin 0 * :Boy Wonder").Join("#gotham")
+
+	c.Drain()
+	c.Send("INVITE Robin #gotham")
+	have := c.Recv()
+	want := ":irc.localhost 443 Batman Robin #gotham :is already on channel"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestInvitationMask(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+
+	c.Send("MODE #gotham +iI Robin")
+	c.WaitFor(irc.ModeCmd)
+	c2.Join("#gotham")
+	if c2.Err() != nil {
+		t.Fatalf("unexpected error: %v", c2.Err())
+	}
+
+	c.Drain()
+	c.Send("MODE #gotham +I")
+	have := c.Recv()
+	want := ":irc.localhost 346 Batman #gotham :Robin!*@*"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	have = c.Recv()
+	want = ":irc.localhost 347 Batman #gotham :End of Channel Invite List"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
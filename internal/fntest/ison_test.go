@@ -0,0 +1,67 @@
+package fntest
+
+import (
+	"testing"
+
+	"github.com/blackchip-org/chatty/internal/tester"
+	"github.com/blackchip-org/chatty/irc"
+)
+
+func TestIson(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne")
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+	c.Send("ISON robin Joker :batman")
+	have := c.Recv()
+	want := ":irc.localhost 303 Batman :Robin Batman"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestIsonNone(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.LoginDefault()
+	c.Send("ISON :Joker Riddler")
+	have := c.Recv()
+	want := ":irc.localhost 303 Batman :"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestUserHost(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne")
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+	c2.Send("AWAY :Homework")
+	c2.WaitFor(irc.RplNowAway)
+
+	c.Send("USERHOST Batman Joker Robin")
+	have := c.Recv()
+	want := ":irc.localhost 302 Batman :Batman=+~batman@irc.localhost Robin=-~robin@irc.localhost"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestUserHostMaxNicks(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.LoginDefault()
+	c.Send("USERHOST Joker Riddler Penguin Bane Scarecrow Batman")
+	have := c.Recv()
+	want := ":irc.localhost 302 Batman :"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
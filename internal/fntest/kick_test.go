@@ -0,0 +1,108 @@
+package fntest
+
+import (
+	"testing"
+
+	"github.com/blackchip-org/chatty/internal/tester"
+	"github.com/blackchip-org/chatty/irc"
+)
+
+func TestKick(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Joker", "joker 0 * :The Joker").Join("#gotham")
+	c.WaitFor(irc.JoinCmd)
+
+	c.Send("KICK #gotham Joker :Back to Arkham")
+	have := c.Recv()
+	want := ":Batman!~batman@localhost KICK #gotham Joker :Back to Arkham"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	have = c2.Recv()
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+
+	c.Send("NAMES #gotham")
+	have = c.Recv()
+	want = ":irc.localhost 353 Batman = #gotham :@Batman"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestKickMultiple(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+	c3 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Joker", "joker 0 * :The Joker").Join("#gotham")
+	c3.Login("Riddler", "riddler 0 * :Edward Nygma").Join("#gotham")
+	c.WaitFor(irc.JoinCmd)
+	c.WaitFor(irc.JoinCmd)
+
+	c.Send("KICK #gotham Joker,Riddler")
+	c.WaitFor(irc.KickCmd)
+	c.WaitFor(irc.KickCmd)
+
+	c.Send("NAMES #gotham")
+	have := c.Recv()
+	want := ":irc.localhost 353 Batman = #gotham :@Batman"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestKickNotOper(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Joker", "joker 0 * :The Joker").Join("#gotham")
+
+	c2.Send("KICK #gotham Batman")
+	have := c2.Recv()
+	want := ":irc.localhost 482 Joker #gotham :You're not channel operator"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestKickNotInChannel(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Joker", "joker 0 * :The Joker")
+
+	c.Send("KICK #gotham Joker")
+	have := c.Recv()
+	want := ":irc.localhost 441 Batman Joker #gotham :They aren't on that channel"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestKickRejoin(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Joker", "joker 0 * :The Joker").Join("#gotham")
+
+	c.Send("KICK #gotham Joker")
+	c2.WaitFor(irc.KickCmd)
+	c2.Join("#gotham")
+	if c2.Err() != nil {
+		t.Fatalf("unexpected error: %v", c2.Err())
+	}
+}
@@ -0,0 +1,96 @@
+package fntest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/blackchip-org/chatty/internal/tester"
+	"github.com/blackchip-org/chatty/irc"
+)
+
+func TestList(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c.Send("TOPIC #gotham :Gotham City News")
+	c.WaitFor(irc.TopicCmd)
+	c2.Login("Robin", "robin 0 * :Boy Wonder").Join("#gotham")
+
+	c2.Send("LIST")
+	wants := []string{
+		":irc.localhost 322 Robin #gotham 2 :Gotham City News",
+		":irc.localhost 323 Robin :End of LIST",
+	}
+	for _, want := range wants {
+		have := c2.Recv()
+		if want != have {
+			t.Fatalf("\n want: %v \n have: %v", want, have)
+		}
+	}
+}
+
+func TestListFilters(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham").Join("#batcave")
+	c2.Login("Robin", "robin 0 * :Boy Wonder").Join("#gotham")
+
+	c2.Send("LIST >1")
+	wants := []string{
+		":irc.localhost 322 Robin #gotham 2 :",
+		":irc.localhost 323 Robin :End of LIST",
+	}
+	for _, want := range wants {
+		have := c2.Recv()
+		if want != have {
+			t.Fatalf("\n want: %v \n have: %v", want, have)
+		}
+	}
+
+	c2.Send("LIST #bat*")
+	wants = []string{
+		":irc.localhost 322 Robin #batcave 1 :",
+		":irc.localhost 323 Robin :End of LIST",
+	}
+	for _, want := range wants {
+		have := c2.Recv()
+		if want != have {
+			t.Fatalf("\n want: %v \n have: %v", want, have)
+		}
+	}
+}
+
+func TestListSecret(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#batcave")
+	c.Send("MODE #batcave +s")
+	c.WaitFor(irc.ModeCmd)
+	c2.Login("Joker", "joker 0 * :The Joker")
+
+	c2.Send("LIST")
+	have := c2.Recv()
+	want := ":irc.localhost 323 Joker :End of LIST"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestISupportElist(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.Send("NICK Batman")
+	c.Send("USER batman 0 * :Bruce Wayne")
+	have := c.WaitFor(irc.RplISupport).Encode()
+	want := " ELIST=MNTU "
+	if !strings.Contains(have, want) {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
@@ -0,0 +1,33 @@
+package fntest
+
+import (
+	"testing"
+
+	"github.com/blackchip-org/chatty/internal/tester"
+)
+
+func TestMotdMissing(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.LoginDefault()
+	c.Send("MOTD")
+	have := c.Recv()
+	want := ":irc.localhost 422 Batman :MOTD File is missing"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestSetMotdNotOper(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.LoginDefault()
+	c.Send("SETMOTD :Welcome to Gotham")
+	have := c.Recv()
+	want := ":irc.localhost 481 Batman :Permission Denied- You're not an IRC operator"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
@@ -0,0 +1,105 @@
+package fntest
+
+import (
+	"testing"
+
+	"github.com/blackchip-org/chatty/internal/tester"
+	"github.com/blackchip-org/chatty/irc"
+)
+
+func TestNickChange(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Robin", "robin 0 * :Dick Grayson").Join("#gotham")
+	c2.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c.WaitFor(irc.JoinCmd)
+
+	c.Send("NICK Nightwing")
+	have := c.Recv()
+	want := ":Robin!~robin@localhost NICK :Nightwing"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	have = c2.Recv()
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+
+	c2.Send("NAMES #gotham")
+	have = AnyOf(c2.Recv(), "@Nightwing", "Batman")
+	want = ":irc.localhost 353 X = #gotham :X X"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestNickChangeInUse(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Robin", "robin 0 * :Dick Grayson")
+	c2.Login("Batman", "batman 0 * :Bruce Wayne")
+
+	c.Send("NICK Batman")
+	have := c.Recv()
+	want := ":irc.localhost 433 Robin Batman :Nickname is already in use"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestNickChangeReleasesOld(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Robin", "robin 0 * :Dick Grayson")
+	c.Send("NICK Nightwing")
+	c.WaitFor(irc.NickCmd)
+
+	c2.Login("Batman", "batman 0 * :Bruce Wayne")
+	c2.Send("PRIVMSG Nightwing :Nice suit")
+	have := c.Recv()
+	want := ":Batman!~batman@localhost PRIVMSG Nightwing :Nice suit"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	c2.Send("PRIVMSG Robin :Hello?")
+	have = c2.Recv()
+	want = ":irc.localhost 401 Batman Robin :No such nick/channel"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestNickErroneous(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.LoginDefault()
+	c.Send("NICK #gotham")
+	have := c.Recv()
+	want := ":irc.localhost 432 Batman #gotham :Erroneous nickname"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestNickBeforeRegistration(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Send("NICK Robin")
+	c.Send("NICK Nightwing")
+	c.Send("USER robin 0 * :Dick Grayson")
+	c.WaitFor(irc.RplWelcome)
+
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+	if c2.Err() != nil {
+		t.Fatalf("unexpected error: %v", c2.Err())
+	}
+}
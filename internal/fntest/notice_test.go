@@ -0,0 +1,60 @@
+package fntest
+
+import (
+	"testing"
+
+	"github.com/blackchip-org/chatty/internal/tester"
+	"github.com/blackchip-org/chatty/irc"
+)
+
+func TestNoticeUser(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne")
+	c2 := s.NewClient()
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+
+	c2.Send("NOTICE Batman :The Bat-Signal is lit")
+	have := c.Recv()
+	want := ":Robin!~robin@localhost NOTICE Batman :The Bat-Signal is lit"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestNoticeChannel(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2 := s.NewClient()
+	c2.Login("Robin", "robin 0 * :Boy Wonder").Join("#gotham")
+	c.WaitFor(irc.JoinCmd)
+
+	c2.Send("NOTICE #gotham :The Bat-Signal is lit")
+	have := c.Recv()
+	want := ":Robin!~robin@localhost NOTICE #gotham :The Bat-Signal is lit"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestNoticeNoReply(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne")
+	c2 := s.NewClient()
+	c2.Login("Robin", "robin 0 * :Boy Wonder").Join("#batcave")
+
+	c.Send("NOTICE Joker :Where are you?")
+	c.Send("NOTICE #batcave :Can you hear me now?")
+	c.Send("NOTICE")
+	c.Send("PING hello")
+	have := c.Recv()
+	want := ":irc.localhost PONG irc.localhost :hello"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
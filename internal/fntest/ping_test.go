@@ -2,6 +2,7 @@ package fntest
 
 import (
 	"testing"
+	"time"
 
 	"github.com/blackchip-org/chatty/internal/tester"
 	"github.com/blackchip-org/chatty/irc"
@@ -32,3 +33,23 @@ func TestPingWithParams(t *testing.T) {
 		t.Fatalf("\n want: %v \n have: %v", want, have)
 	}
 }
+
+func TestPingTimeout(t *testing.T) {
+	s, c := tester.NewServerConfig(t, func(s *irc.Server) {
+		s.PingInterval = 150 * time.Millisecond
+		s.PingTimeout = 150 * time.Millisecond
+	})
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Robin", "robin 0 * :Boy Wonder").Join("#gotham")
+
+	c.WaitFor(irc.PingCmd)
+	c.Send("PONG irc.localhost")
+	have := c.WaitFor(irc.QuitCmd).Encode()
+	want := ":Robin!~robin@localhost QUIT :Ping timeout"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
@@ -0,0 +1,74 @@
+package fntest
+
+import (
+	"testing"
+
+	"github.com/blackchip-org/chatty/internal/tester"
+	"github.com/blackchip-org/chatty/irc"
+)
+
+func TestPrivMsgUser(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne")
+	c2 := s.NewClient()
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+
+	c2.Send("PRIVMSG Batman :Holy hamburger Batman!")
+	have := c.Recv()
+	want := ":Robin!~robin@localhost PRIVMSG Batman :Holy hamburger Batman!"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestPrivMsgNoSuchNick(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.LoginDefault()
+	c.Send("PRIVMSG Joker :Where are you?")
+	have := c.Recv()
+	want := ":irc.localhost 401 Batman Joker :No such nick/channel"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestPrivMsgMultipleTargets(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2 := s.NewClient()
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+	c3 := s.NewClient()
+	c3.Login("Alfred", "alfred 0 * :Alfred Pennyworth").Join("#gotham")
+	c.WaitFor(irc.JoinCmd)
+
+	c2.Send("PRIVMSG Batman,#gotham :Dinner is served")
+	have := c.Recv()
+	want := ":Robin!~robin@localhost PRIVMSG Batman :Dinner is served"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	have = c2.Recv()
+	want = ":irc.localhost 404 Robin #gotham :Cannot send to channel"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestPrivMsgNoText(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.LoginDefault()
+	c.Send("PRIVMSG Batman")
+	have := c.Recv()
+	want := ":irc.localhost 412 Batman :No text to send"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
@@ -0,0 +1,117 @@
+package fntest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/blackchip-org/chatty/internal/tester"
+	"github.com/blackchip-org/chatty/irc"
+)
+
+func TestLusers(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.LoginDefault().Join("#gotham")
+	c.Send("LUSERS")
+	wants := []string{
+		":irc.localhost 251 Batman :There are 1 users and 0 services on 1 servers",
+		":irc.localhost 254 Batman 1 :channels formed",
+		":irc.localhost 255 Batman :I have 1 users, 0 services and 0 servers",
+		":irc.localhost 265 Batman 1 1 :Current local users 1, max 1",
+		":irc.localhost 266 Batman 1 1 :Current global users 1, max 1",
+	}
+	for _, want := range wants {
+		have := c.Recv()
+		if want != have {
+			t.Fatalf("\n want: %v \n have: %v", want, have)
+		}
+	}
+}
+
+func TestLusersOnRegistration(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.Send("NICK Batman")
+	c.Send("USER batman 0 * :Bruce Wayne")
+	have := c.WaitFor(irc.RplLuserMe).Encode()
+	want := ":irc.localhost 255 Batman :I have 1 users, 0 services and 0 servers"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.LoginDefault()
+	c.Send("VERSION")
+	have := c.Recv()
+	want := ":irc.localhost 351 Batman " + irc.Version + " irc.localhost :"
+	if !strings.HasPrefix(have, want) {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	c.WaitFor(irc.RplISupport)
+	if c.Err() != nil {
+		t.Fatalf("unexpected error: %v", c.Err())
+	}
+}
+
+func TestTime(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.LoginDefault()
+	c.Send("TIME")
+	have := c.Recv()
+	want := ":irc.localhost 391 Batman irc.localhost :"
+	if !strings.HasPrefix(have, want) {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestAdminNoInfo(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.LoginDefault()
+	c.Send("ADMIN")
+	have := c.Recv()
+	want := ":irc.localhost 423 Batman irc.localhost :No administrative info available"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestInfo(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.LoginDefault()
+	c.Send("INFO")
+	have := c.Recv()
+	want := ":irc.localhost 371 Batman :irc.localhost version " + irc.Version
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	have = c.WaitFor(irc.RplEndOfInfo).Encode()
+	want = ":irc.localhost 374 Batman :End of INFO list"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestStatsNotOper(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.LoginDefault()
+	c.Send("STATS l")
+	have := c.Recv()
+	want := ":irc.localhost 481 Batman :Permission Denied- You're not an IRC operator"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
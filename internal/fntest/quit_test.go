@@ -0,0 +1,86 @@
+package fntest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blackchip-org/chatty/internal/tester"
+	"github.com/blackchip-org/chatty/irc"
+)
+
+func TestQuitConnectionReset(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Robin", "robin 0 * :Boy Wonder").Join("#gotham")
+
+	c2.Disconnect()
+	have := c.WaitFor(irc.QuitCmd).Encode()
+	want := ":Robin!~robin@localhost QUIT :Connection reset"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+
+	c.Send("NAMES #gotham")
+	have = c.Recv()
+	want = ":irc.localhost 353 Batman = #gotham :@Batman"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestQuitInputTooLong(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Joker", "joker 0 * :The Joker").Join("#gotham")
+
+	c2.Send("PRIVMSG #gotham :" + strings.Repeat("HA", irc.MessageMaxLen))
+	have := c.WaitFor(irc.QuitCmd).Encode()
+	want := ":Joker!~joker@localhost QUIT :Input line too long"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestQuitRegistrationTimeout(t *testing.T) {
+	s, c := tester.NewServerConfig(t, func(s *irc.Server) {
+		s.RegistrationDeadline = 100 * time.Millisecond
+	})
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.LoginDefault()
+	c2.Send("NICK Robin")
+	have := c2.WaitFor(irc.ErrorCmd).Encode()
+	want := "ERROR :Closing Link (Registration timeout)"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+
+	// Clients that never registered are not remembered
+	c.Send("WHOWAS Robin")
+	have = c.Recv()
+	want = ":irc.localhost 406 Batman Robin :There was no such nickname"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestQuitError(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.LoginDefault()
+	c.Send("QUIT :Holy exit!")
+	have := c.Recv()
+	want := "ERROR :Closing Link (Holy exit!)"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
@@ -87,3 +87,31 @@ func TestAlreadyRegisteredPass(t *testing.T) {
 		t.Fatalf("\n want: %v \n have: %v \n err:  %v", want, have, c.Err())
 	}
 }
+
+func TestMyInfo(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c.Send("NICK Batman")
+	c.Send("USER Batman 0 * :Bruce Wayne")
+
+	want := "Batman irc.localhost " + irc.Version + " aio beiIklmnostv beIklov"
+	have := strings.Join(c.WaitFor(irc.RplMyInfo).Params, " ")
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v \n err:  %v", want, have, c.Err())
+	}
+}
+
+func TestISupport(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c.Send("NICK Batman")
+	c.Send("USER Batman 0 * :Bruce Wayne")
+
+	want := ":irc.localhost 005 Batman CASEMAPPING=rfc1459 CHANMODES=beI,k,l,imnst " +
+		"CHANNELLEN=50 CHANTYPES=#& ELIST=MNTU EXCEPTS=e INVEX=I MAXLIST=beI:64 MODES=3 " +
+		"NICKLEN=40 PREFIX=(ov)@+ TOPICLEN=390 :are supported by this server"
+	have := c.WaitFor(irc.RplISupport).Encode()
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v \n err:  %v", want, have, c.Err())
+	}
+}
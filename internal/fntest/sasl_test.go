@@ -0,0 +1,210 @@
+package fntest
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"testing"
+
+	"github.com/blackchip-org/chatty/internal/security"
+	"github.com/blackchip-org/chatty/internal/tester"
+	"github.com/blackchip-org/chatty/irc"
+)
+
+func TestSaslMechUnknown(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.Send("CAP REQ :sasl")
+	c.WaitFor(irc.CapCmd)
+	c.Send("AUTHENTICATE SCRAM-SHA-256")
+	have := c.Recv()
+	want := ":irc.localhost 908 * PLAIN :are available SASL mechanisms"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	have = c.Recv()
+	want = ":irc.localhost 904 * :SASL authentication failed"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestSaslPlainFail(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.Send("CAP REQ :sasl")
+	c.WaitFor(irc.CapCmd)
+	c.Send("AUTHENTICATE PLAIN")
+	have := c.Recv()
+	want := ":irc.localhost AUTHENTICATE +"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	c.Send("AUTHENTICATE " + base64.StdEncoding.EncodeToString([]byte("\x00Joker\x00hahaha")))
+	have = c.Recv()
+	want = ":irc.localhost 904 * :SASL authentication failed"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestSaslTooManyFails(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.Send("CAP REQ :sasl")
+	c.WaitFor(irc.CapCmd)
+	for i := 0; i < irc.SaslMaxFails; i++ {
+		c.Send("AUTHENTICATE PLAIN")
+		c.WaitFor(irc.AuthenticateCmd)
+		c.Send("AUTHENTICATE " + base64.StdEncoding.EncodeToString([]byte("\x00Joker\x00hahaha")))
+		c.WaitFor(irc.ErrSaslFail)
+	}
+	have := c.Recv()
+	want := "ERROR :Closing Link (Too many SASL failures)"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestSaslAbort(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.Send("CAP REQ :sasl")
+	c.WaitFor(irc.CapCmd)
+	c.Send("AUTHENTICATE PLAIN")
+	c.WaitFor(irc.AuthenticateCmd)
+	c.Send("AUTHENTICATE *")
+	have := c.Recv()
+	want := ":irc.localhost 906 * :SASL authentication aborted"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestSaslWithoutCap(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.Send("AUTHENTICATE PLAIN")
+	have := c.Recv()
+	want := ":irc.localhost 904 * :SASL authentication failed"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestSaslRequired(t *testing.T) {
+	s, c := tester.NewServerConfig(t, func(s *irc.Server) {
+		s.RequireSASL = true
+	})
+	defer s.Quit()
+
+	c.Send("NICK Joker")
+	c.Send("USER joker 0 * :The Joker")
+	have := c.Recv()
+	want := "ERROR :Closing Link (SASL authentication required)"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+// newTLSServer starts a server that only accepts TLS connections. The
+// client returned by tester.NewServerConfig does not use TLS and is not
+// needed.
+func newTLSServer(t *testing.T) *tester.Server {
+	s, _ := newAccountServer(t, func(s *irc.Server) {
+		s.Insecure = false
+		cert, key, err := security.SelfSignCert()
+		if err != nil {
+			t.Fatal(err)
+		}
+		putConfig(t, s.DataFile, map[string][]byte{
+			string(irc.ConfigCert): cert,
+			string(irc.ConfigKey):  key,
+		})
+	})
+	return s
+}
+
+// clientCert returns a new self-signed client certificate and its
+// fingerprint.
+func clientCert(t *testing.T) (tls.Certificate, string) {
+	certPem, keyPem, err := security.SelfSignCert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair(certPem, keyPem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, security.Fingerprint(cert.Certificate[0])
+}
+
+func TestSaslExternal(t *testing.T) {
+	if tester.RealServer {
+		t.Skip("skipping test on real server")
+	}
+	s := newTLSServer(t)
+	defer s.Quit()
+	cert, _ := clientCert(t)
+	config := &tls.Config{
+		InsecureSkipVerify: true,
+		Certificates:       []tls.Certificate{cert},
+	}
+
+	c := s.NewClientTLS(config)
+	login(c, "Batman", "batman 0 * :Bruce Wayne", "alfred123", true)
+	c.Send("CERTFP")
+	have := c.Recv()
+	want := ":irc.localhost CERTFP SUCCESS Batman :Certificate fingerprint updated"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+
+	c2 := s.NewClientTLS(config)
+	c2.Send("CAP REQ :sasl")
+	c2.WaitFor(irc.CapCmd)
+	c2.Send("NICK Bruce")
+	c2.Send("AUTHENTICATE EXTERNAL")
+	c2.WaitFor(irc.AuthenticateCmd)
+	c2.Send("AUTHENTICATE +")
+	have = c2.Recv()
+	want = ":irc.localhost 900 Bruce Bruce!~*@irc.localhost Batman :You are now logged in as Batman"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	c2.WaitFor(irc.RplSaslSuccess)
+
+	// A certificate that is not on an account
+	other, _ := clientCert(t)
+	c3 := s.NewClientTLS(&tls.Config{
+		InsecureSkipVerify: true,
+		Certificates:       []tls.Certificate{other},
+	})
+	c3.Send("CAP REQ :sasl")
+	c3.WaitFor(irc.CapCmd)
+	c3.Send("AUTHENTICATE EXTERNAL")
+	c3.WaitFor(irc.AuthenticateCmd)
+	c3.Send("AUTHENTICATE +")
+	have = c3.Recv()
+	want = ":irc.localhost 904 * :SASL authentication failed"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestCertFPWithoutCert(t *testing.T) {
+	s, c := newAccountServer(t, nil)
+	defer s.Quit()
+
+	login(c, "Batman", "batman 0 * :Bruce Wayne", "alfred123", true)
+	c.Send("CERTFP")
+	have := c.Recv()
+	want := ":irc.localhost FAIL CERTFP NO_CERTIFICATE :Connect with a client certificate to set it"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
@@ -0,0 +1,70 @@
+package fntest
+
+import (
+	"testing"
+
+	"github.com/blackchip-org/chatty/internal/tester"
+	"github.com/blackchip-org/chatty/irc"
+)
+
+func TestTagMsg(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+	c2.Send("CAP REQ :message-tags")
+	c2.WaitFor(irc.CapCmd)
+	c2.Join("#gotham")
+
+	c.Send("@+typing=active TAGMSG #gotham")
+	have := c2.Recv()
+	want := "@+typing=active :Batman!~batman@localhost TAGMSG :#gotham"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestTagMsgNoCap(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Robin", "robin 0 * :Boy Wonder").Join("#gotham")
+
+	c.Send("@+typing=active TAGMSG #gotham")
+	c.Send("PRIVMSG #gotham :To the Batmobile!")
+	have := c2.WaitForAny([]string{irc.TagMsgCmd, irc.PrivMsgCmd}).Encode()
+	want := ":Batman!~batman@localhost PRIVMSG #gotham :To the Batmobile!"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestPrivMsgClientTags(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+	c3 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Robin", "robin 0 * :Boy Wonder")
+	c2.Send("CAP REQ :message-tags")
+	c2.WaitFor(irc.CapCmd)
+	c2.Join("#gotham")
+	c3.Login("Alfred", "alfred 0 * :Alfred Pennyworth").Join("#gotham")
+
+	c.Send("@+draft/reply=123;time=now PRIVMSG #gotham :To the Batmobile!")
+	have := c2.WaitFor(irc.PrivMsgCmd).Encode()
+	want := "@+draft/reply=123 :Batman!~batman@localhost PRIVMSG #gotham :To the Batmobile!"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	have = c3.WaitFor(irc.PrivMsgCmd).Encode()
+	want = ":Batman!~batman@localhost PRIVMSG #gotham :To the Batmobile!"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
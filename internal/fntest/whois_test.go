@@ -0,0 +1,83 @@
+package fntest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/blackchip-org/chatty/internal/tester"
+	"github.com/blackchip-org/chatty/irc"
+)
+
+func TestWhois(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Robin", "robin 0 * :Boy Wonder").Join("#gotham")
+
+	c.Drain()
+	c2.Send("WHOIS Batman")
+	have := c2.Recv()
+	want := ":irc.localhost 311 Robin Batman ~batman irc.localhost * :Bruce Wayne"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	have = c2.Recv()
+	want = ":irc.localhost 319 Robin Batman :@#gotham"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	have = c2.Recv()
+	want = ":irc.localhost 312 Robin Batman irc.localhost :" + irc.Version
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	have = c2.Recv()
+	want = ":irc.localhost 317 Robin Batman "
+	if !strings.HasPrefix(have, want) {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	have = c2.Recv()
+	want = ":irc.localhost 318 Robin Batman :End of WHOIS list."
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestWhoisNoSuchNick(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.LoginDefault()
+	c.Send("WHOIS Joker")
+	have := c.Recv()
+	want := ":irc.localhost 401 Batman Joker :No such nick/channel"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	have = c.Recv()
+	want = ":irc.localhost 318 Batman Joker :End of WHOIS list."
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestWhoisSecretChannel(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#batcave")
+	c2.Login("Joker", "joker 0 * :The Joker")
+	c.Send("MODE #batcave +s")
+	c.WaitFor(irc.ModeCmd)
+
+	c2.Send("WHOIS Batman")
+	c2.WaitFor(irc.RplWhoisUser)
+	have := c2.Recv()
+	want := ":irc.localhost 312 Joker Batman irc.localhost :" + irc.Version
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
@@ -0,0 +1,70 @@
+package fntest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/blackchip-org/chatty/internal/tester"
+	"github.com/blackchip-org/chatty/irc"
+)
+
+func TestWhoWas(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Batman", "batman 0 * :Bruce Wayne").Join("#gotham")
+	c2.Login("Joker", "joker 0 * :The Joker").Join("#gotham")
+	c2.Send("QUIT")
+	c.WaitFor(irc.QuitCmd)
+
+	c.Send("WHOWAS Joker")
+	have := c.WaitFor(irc.RplWhoWasUser).Encode()
+	want := ":irc.localhost 314 Batman Joker ~joker irc.localhost * :The Joker"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	have = c.Recv()
+	want = ":irc.localhost 312 Batman Joker irc.localhost :"
+	if !strings.HasPrefix(have, want) {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	have = c.Recv()
+	want = ":irc.localhost 369 Batman Joker :End of WHOWAS"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestWhoWasNoSuchNick(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+
+	c.LoginDefault()
+	c.Send("WHOWAS Riddler")
+	have := c.Recv()
+	want := ":irc.localhost 406 Batman Riddler :There was no such nickname"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+	have = c.Recv()
+	want = ":irc.localhost 369 Batman Riddler :End of WHOWAS"
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestNickReclaimProtection(t *testing.T) {
+	s, c := tester.NewServer(t)
+	defer s.Quit()
+	c2 := s.NewClient()
+
+	c.Login("Joker", "joker 0 * :The Joker")
+	c.Send("QUIT")
+
+	c2.Send("NICK Joker")
+	c2.WaitFor(irc.ErrNickNameInUse)
+	if c2.Err() != nil {
+		t.Fatalf("unexpected error: %v", c2.Err())
+	}
+}
@@ -3,8 +3,10 @@ package security
 import (
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/hex"
 	"encoding/pem"
 	"fmt"
 	"math/big"
@@ -50,3 +52,10 @@ func SelfSignCert() ([]byte, []byte, error) {
 	})
 	return cert, key, nil
 }
+
+// Fingerprint returns the SHA-256 hash of a DER encoded certificate as a
+// lowercase hex string.
+func Fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
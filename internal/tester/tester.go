@@ -2,6 +2,7 @@ package tester
 
 import (
 	"bufio"
+	"crypto/tls"
 	"errors"
 	"flag"
 	"fmt"
@@ -25,7 +26,10 @@ func init() {
 	flag.BoolVar(&RealServer, "real-server", false, "run tests using a real server")
 }
 
-var errRecvTimeout = errors.New("recv timeout")
+var (
+	errRecvTimeout = errors.New("recv timeout")
+	errLinkClosed  = errors.New("link closed by server")
+)
 
 type Server struct {
 	Actual    *irc.Server
@@ -41,6 +45,7 @@ type Client struct {
 	id     int
 	conn   net.Conn
 	recvq  chan string
+	closed bool
 	w      *bufio.Writer
 	debug  bool
 	err    error
@@ -50,6 +55,12 @@ type Client struct {
 }
 
 func NewServer(t *testing.T) (*Server, *Client) {
+	return NewServerConfig(t, nil)
+}
+
+// NewServerConfig is like NewServer but calls config, if not nil, to adjust
+// the server settings before it is started.
+func NewServerConfig(t *testing.T, config func(*irc.Server)) (*Server, *Client) {
 	addr := ":" + strconv.Itoa(nextPort)
 	if !RealServer {
 		nextPort++
@@ -70,6 +81,9 @@ func NewServer(t *testing.T) (*Server, *Client) {
 		timeStart: time.Now(),
 	}
 	ts.Actual = ts.server
+	if config != nil {
+		config(ts.server)
+	}
 	if !RealServer {
 		go func() {
 			retries := 0
@@ -98,6 +112,12 @@ func NewServer(t *testing.T) (*Server, *Client) {
 }
 
 func (s *Server) NewClient() *Client {
+	return s.NewClientTLS(nil)
+}
+
+// NewClientTLS is like NewClient but connects with TLS when config is not
+// nil.
+func (s *Server) NewClientTLS(config *tls.Config) *Client {
 	tc := &Client{
 		recvq:  make(chan string, 1024),
 		t:      s.t,
@@ -110,7 +130,7 @@ func (s *Server) NewClient() *Client {
 		tc.err = s.err
 		return tc
 	}
-	err := tc.connect(s.server.Addr)
+	err := tc.connect(s.server.Addr, config)
 	if err != nil {
 		tc.err = err
 		return tc
@@ -143,10 +163,23 @@ func (s *Server) Quit() {
 	}
 }
 
-func (c *Client) connect(addr string) error {
+// Disconnect closes the connection without sending a QUIT.
+func (c *Client) Disconnect() {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+}
+
+func (c *Client) connect(addr string, config *tls.Config) error {
 	retries := 0
 	for {
-		conn, err := net.Dial("tcp", addr)
+		var conn net.Conn
+		var err error
+		if config != nil {
+			conn, err = tls.Dial("tcp", addr, config)
+		} else {
+			conn, err = net.Dial("tcp", addr)
+		}
 		if err != nil {
 			if retries >= 9 {
 				return err
@@ -204,6 +237,10 @@ func (c *Client) Recv() string {
 	case line := <-c.recvq:
 		line = normalizeLine(line)
 		c.logf("<- ", line)
+		// The server closes the connection after an ERROR
+		if strings.HasPrefix(line, irc.ErrorCmd+" ") {
+			c.closed = true
+		}
 		return line
 	case <-timer.C:
 		c.err = errRecvTimeout
@@ -299,6 +336,9 @@ func (c *Client) Join(chname string) *Client {
 }
 
 func (c *Client) Err() error {
+	if c.err == nil && c.closed {
+		return errLinkClosed
+	}
 	return c.err
 }
 
@@ -311,6 +351,11 @@ func (c *Client) logf(prefix string, format string, params ...interface{}) {
 // Replace server specific host info with localhost for testing
 func normalizeLine(line string) string {
 	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "@") {
+		if i := strings.Index(line, " "); i > 0 {
+			return line[:i+1] + normalizeLine(line[i+1:])
+		}
+	}
 	if !strings.HasPrefix(line, ":") {
 		return line
 	}
@@ -0,0 +1,255 @@
+package irc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/blackchip-org/chatty/internal/security"
+	"github.com/boltdb/bolt"
+)
+
+// AccountPassMinLen is the shortest password accepted by REGISTER.
+const AccountPassMinLen = 8
+
+// DefaultNickGrace is how long a user has to log in before losing a nick
+// that belongs to an account.
+const DefaultNickGrace = time.Minute
+
+var (
+	errAccountExists  = errors.New("account already exists")
+	errNoSuchAccount  = errors.New("no such account")
+	errAuthFailed     = errors.New("authentication failed")
+	errBadAccountName = errors.New("invalid account name")
+	errWeakPassword   = errors.New("password too short")
+	errCertInUse      = errors.New("certificate used by another account")
+)
+
+// Each account is a bucket in BucketAccounts keyed by the folded account
+// name. Passwords are stored as PBKDF2 hashes.
+
+// CreateAccount stores a new account with the given password.
+func (s *Service) CreateAccount(name string, password string) error {
+	salt, err := security.Salt()
+	if err != nil {
+		return err
+	}
+	pass := security.EncodePassword([]byte(password), salt)
+	return s.db.Update(func(tx *bolt.Tx) error {
+		key := []byte(s.fold(name))
+		accounts := tx.Bucket(BucketAccounts)
+		if accounts.Bucket(key) != nil {
+			return errAccountExists
+		}
+		account, err := accounts.CreateBucket(key)
+		if err != nil {
+			return err
+		}
+		account.Put(AccountName, []byte(name))
+		account.Put(AccountPass, pass)
+		account.Put(AccountSalt, salt)
+		return nil
+	})
+}
+
+// Register creates an account and logs the client in to it. The name cannot
+// be the nick of another user.
+func (s *Service) Register(c *Client, name string, password string) error {
+	if !ValidNick(name) || len(name) > NickMaxLen {
+		return errBadAccountName
+	}
+	if len(password) < AccountPassMinLen {
+		return errWeakPassword
+	}
+	if u, exists := s.nicks.Get(name); exists && u.ID != c.User.ID {
+		return errBadAccountName
+	}
+	if err := s.CreateAccount(name, password); err != nil {
+		return err
+	}
+	s.logIn(c, name)
+	return nil
+}
+
+// logIn sets the account of the client. Once logged in, the nick of the
+// client is no longer at risk of being changed and it is given its access
+// in the registered channels it has already joined.
+func (s *Service) logIn(c *Client, account string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	c.User.Account = account
+	s.stopNickTimer(c)
+	for _, ch := range c.chans {
+		ch.LogIn(s, c)
+	}
+}
+
+// SetAccountCert sets the fingerprint of the client certificate that logs
+// in to the account with SASL EXTERNAL. An empty fingerprint removes it.
+// Each fingerprint belongs to one account and is indexed in BucketCertFPs
+// so that it can be found without reading every account.
+func (s *Service) SetAccountCert(name string, certfp string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		key := []byte(s.fold(name))
+		account := tx.Bucket(BucketAccounts).Bucket(key)
+		if account == nil {
+			return errNoSuchAccount
+		}
+		certfps := tx.Bucket(BucketCertFPs)
+		fp := []byte(normalizeCertFP(certfp))
+		if owner := certfps.Get(fp); len(fp) > 0 && owner != nil && !bytes.Equal(owner, key) {
+			return errCertInUse
+		}
+		if prev := account.Get(AccountCertFP); prev != nil {
+			if err := certfps.Delete(prev); err != nil {
+				return err
+			}
+		}
+		if len(fp) == 0 {
+			return account.Delete(AccountCertFP)
+		}
+		if err := account.Put(AccountCertFP, fp); err != nil {
+			return err
+		}
+		return certfps.Put(fp, key)
+	})
+}
+
+// authPlain checks the password for an account and returns the account
+// name.
+func (s *Service) authPlain(name string, password string) (string, error) {
+	var accountName string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		account := tx.Bucket(BucketAccounts).Bucket([]byte(s.fold(name)))
+		if account == nil {
+			return errNoSuchAccount
+		}
+		pass := account.Get(AccountPass)
+		salt := account.Get(AccountSalt)
+		if !bytes.Equal(pass, security.EncodePassword([]byte(password), salt)) {
+			return errAuthFailed
+		}
+		accountName = string(account.Get(AccountName))
+		return nil
+	})
+	return accountName, err
+}
+
+// authCert finds the account that has the client certificate fingerprint
+// and returns the account name.
+func (s *Service) authCert(certfp string) (string, error) {
+	if certfp == "" {
+		return "", errAuthFailed
+	}
+	certfp = normalizeCertFP(certfp)
+	var accountName string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		key := tx.Bucket(BucketCertFPs).Get([]byte(certfp))
+		if key == nil {
+			return nil
+		}
+		if account := tx.Bucket(BucketAccounts).Bucket(key); account != nil {
+			accountName = string(account.Get(AccountName))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if accountName == "" {
+		return "", errAuthFailed
+	}
+	return accountName, nil
+}
+
+// protectNick warns a user whose nick is the name of an account they are
+// not logged in to. If the user has not logged in or changed nick by the
+// end of the grace period, the nick is changed.
+func (s *Service) protectNick(c *Client) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.watchNick(c)
+}
+
+// watchNick is protectNick for callers that hold the service mutex. A
+// timer that is already running for the client is replaced.
+func (s *Service) watchNick(c *Client) {
+	s.stopNickTimer(c)
+	nick := c.User.Nick
+	if s.fold(c.User.Account) == s.fold(nick) || !s.accountExists(nick) {
+		return
+	}
+	c.Send(NoticeCmd, nick, fmt.Sprintf("This nick is registered. Log in to the account "+
+		"within %v or your nick will be changed.", s.NickGrace))
+	// The nick is changed by the client's own goroutine as the nick is
+	// read there without holding a lock
+	var timer *time.Timer
+	timer = time.AfterFunc(s.NickGrace, func() {
+		c.queue(func() {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			// Ignore a timer that was stopped or replaced after it fired
+			if c.nickTimer == timer {
+				c.nickTimer = nil
+				s.enforceNick(c, nick)
+			}
+		})
+	})
+	c.nickTimer = timer
+}
+
+// stopNickTimer cancels the nick change for the client. The service mutex
+// must be held by the caller.
+func (s *Service) stopNickTimer(c *Client) {
+	if c.nickTimer != nil {
+		c.nickTimer.Stop()
+		c.nickTimer = nil
+	}
+}
+
+// enforceNick changes the nick at the end of the grace period. The service
+// mutex must be held by the caller.
+func (s *Service) enforceNick(c *Client, nick string) {
+	if _, online := s.clients[c.User.ID]; !online {
+		return
+	}
+	if s.fold(c.User.Nick) != s.fold(nick) || s.fold(c.User.Account) == s.fold(nick) {
+		return
+	}
+	guest := fmt.Sprintf("Guest%v", c.User.ID)
+	if err := s.rename(c, guest); err != nil {
+		c.close("Nick is registered")
+	}
+}
+
+// registerBeforeConnect reports whether accounts can be registered before
+// the connection is complete. It is refused when SASL or a connection
+// password is required, as neither has been checked at that point.
+func (s *Service) registerBeforeConnect() bool {
+	if s.RequireSASL {
+		return false
+	}
+	pass := false
+	s.db.View(func(tx *bolt.Tx) error {
+		pass = tx.Bucket(BucketConfig).Get(ConfigPass) != nil
+		return nil
+	})
+	return !pass
+}
+
+func (s *Service) accountExists(name string) bool {
+	exists := false
+	s.db.View(func(tx *bolt.Tx) error {
+		exists = tx.Bucket(BucketAccounts).Bucket([]byte(s.fold(name))) != nil
+		return nil
+	})
+	return exists
+}
+
+// Fingerprints may be written in upper case and with colons between each
+// byte.
+func normalizeCertFP(certfp string) string {
+	return strings.ToLower(strings.ReplaceAll(certfp, ":", ""))
+}
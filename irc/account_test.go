@@ -0,0 +1,127 @@
+package irc
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func newTestService(t *testing.T) *Service {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range Buckets {
+			tx.CreateBucketIfNotExists(bucket)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := newService("irc.localhost", DefaultCaseMapping, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestAuthPlain(t *testing.T) {
+	s := newTestService(t)
+	if err := s.CreateAccount("Batman", "alfred"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.CreateAccount("BATMAN", "joker"); err != errAccountExists {
+		t.Fatalf("\n want: %v \n have: %v", errAccountExists, err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		account  string
+		err      error
+	}{
+		{"Batman", "alfred", "Batman", nil},
+		{"batman", "alfred", "Batman", nil},
+		{"Batman", "joker", "", errAuthFailed},
+		{"Robin", "alfred", "", errNoSuchAccount},
+	}
+	for _, test := range tests {
+		account, err := s.authPlain(test.name, test.password)
+		if account != test.account || err != test.err {
+			t.Errorf("%v %v\n want: %v, %v \n have: %v, %v", test.name, test.password,
+				test.account, test.err, account, err)
+		}
+	}
+}
+
+func TestAuthCert(t *testing.T) {
+	s := newTestService(t)
+	if err := s.CreateAccount("Batman", "alfred"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.SetAccountCert("Robin", "ab"); err != errNoSuchAccount {
+		t.Fatalf("\n want: %v \n have: %v", errNoSuchAccount, err)
+	}
+	if err := s.SetAccountCert("Batman", "AB:CD:EF"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	account, err := s.authCert("abcdef")
+	if account != "Batman" || err != nil {
+		t.Fatalf("\n want: Batman, <nil> \n have: %v, %v", account, err)
+	}
+	if _, err := s.authCert("abcd"); err != errAuthFailed {
+		t.Fatalf("\n want: %v \n have: %v", errAuthFailed, err)
+	}
+	if _, err := s.authCert(""); err != errAuthFailed {
+		t.Fatalf("\n want: %v \n have: %v", errAuthFailed, err)
+	}
+	if err := s.CreateAccount("Robin", "dick"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.SetAccountCert("Robin", "abcdef"); err != errCertInUse {
+		t.Fatalf("\n want: %v \n have: %v", errCertInUse, err)
+	}
+	if err := s.SetAccountCert("Batman", "123456"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.authCert("abcdef"); err != errAuthFailed {
+		t.Fatalf("\n want: %v \n have: %v", errAuthFailed, err)
+	}
+
+	if err := s.SetAccountCert("Batman", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.authCert("123456"); err != errAuthFailed {
+		t.Fatalf("\n want: %v \n have: %v", errAuthFailed, err)
+	}
+}
+
+func TestNickTimer(t *testing.T) {
+	s := newTestService(t)
+	if err := s.CreateAccount("Batman", "alfred123"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := newTestClient(1000)
+
+	s.protectNick(c)
+	first := c.nickTimer
+	if first == nil {
+		t.Fatalf("expected nick timer")
+	}
+	s.protectNick(c)
+	if c.nickTimer == nil || c.nickTimer == first {
+		t.Fatalf("expected nick timer to be replaced")
+	}
+	if first.Stop() {
+		t.Errorf("expected first timer to be stopped")
+	}
+
+	s.logIn(c, "Batman")
+	if c.nickTimer != nil {
+		t.Errorf("expected nick timer to stop on login")
+	}
+}
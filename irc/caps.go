@@ -0,0 +1,47 @@
+package irc
+
+import (
+	"sort"
+	"strings"
+)
+
+// IRCv3 capabilities that can be requested with CAP REQ.
+// https://ircv3.net/specs/extensions/capability-negotiation
+const (
+	CapAccountReg  = "draft/account-registration"
+	CapAccountTag  = "account-tag"
+	CapAwayNotify  = "away-notify"
+	CapCapNotify   = "cap-notify"
+	CapMessageTags = "message-tags"
+	CapSasl        = "sasl"
+)
+
+// CapVersion is the latest version of capability negotiation supported.
+// Clients that ask for it with CAP LS see capability values and are sent
+// CAP NEW and CAP DEL when the available capabilities change.
+const CapVersion = 302
+
+// DefaultCaps are the capabilities, and their values, available when the
+// service starts.
+var DefaultCaps = map[string]string{
+	CapAccountReg:  "before-connect,custom-account-name",
+	CapAccountTag:  "",
+	CapAwayNotify:  "",
+	CapCapNotify:   "",
+	CapMessageTags: "",
+	CapSasl:        strings.Join(SaslMechs, ","),
+}
+
+// capList formats the capabilities for CAP LS, CAP NEW or CAP DEL. Values
+// are only included for clients using version 302 or later.
+func capList(caps map[string]string, version int) []string {
+	list := make([]string, 0, len(caps))
+	for name, value := range caps {
+		if value != "" && version >= CapVersion {
+			name += "=" + value
+		}
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
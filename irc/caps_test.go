@@ -0,0 +1,28 @@
+package irc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCapList(t *testing.T) {
+	caps := map[string]string{
+		"sasl":         "PLAIN,EXTERNAL",
+		"away-notify":  "",
+		"message-tags": "",
+	}
+	tests := []struct {
+		version int
+		want    []string
+	}{
+		{0, []string{"away-notify", "message-tags", "sasl"}},
+		{301, []string{"away-notify", "message-tags", "sasl"}},
+		{302, []string{"away-notify", "message-tags", "sasl=PLAIN,EXTERNAL"}},
+	}
+	for _, test := range tests {
+		have := capList(caps, test.version)
+		if !reflect.DeepEqual(test.want, have) {
+			t.Errorf("version %v\n want: %v \n have: %v", test.version, test.want, have)
+		}
+	}
+}
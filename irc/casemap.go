@@ -0,0 +1,78 @@
+package irc
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/secure/precis"
+)
+
+// Case mappings advertised with CASEMAPPING in RPL_ISUPPORT.
+// https://modern.ircdocs.horse/#casemapping-parameter
+const (
+	CaseMappingASCII   = "ascii"
+	CaseMappingRFC1459 = "rfc1459"
+	CaseMappingRFC7613 = "rfc7613"
+)
+
+// DefaultCaseMapping is used when the server does not specify one.
+const DefaultCaseMapping = CaseMappingRFC1459
+
+// FoldFunc converts a nick or channel name into the key used for
+// comparisons. The display case of the name is kept elsewhere.
+type FoldFunc func(string) string
+
+var foldFuncs = map[string]FoldFunc{
+	CaseMappingASCII:   FoldASCII,
+	CaseMappingRFC1459: FoldRFC1459,
+	CaseMappingRFC7613: FoldRFC7613,
+}
+
+// CaseMappingFold returns the fold function for the named case mapping.
+func CaseMappingFold(name string) (FoldFunc, error) {
+	fold, ok := foldFuncs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown case mapping: %v", name)
+	}
+	return fold, nil
+}
+
+// FoldASCII lowercases only the letters A to Z.
+func FoldASCII(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' {
+			return r + ('a' - 'A')
+		}
+		return r
+	}, name)
+}
+
+// FoldRFC1459 lowercases like FoldASCII and also treats {}|^ as the lower
+// case equivalents of []\~.
+func FoldRFC1459(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		case r == '[':
+			return '{'
+		case r == ']':
+			return '}'
+		case r == '\\':
+			return '|'
+		case r == '~':
+			return '^'
+		}
+		return r
+	}, name)
+}
+
+// FoldRFC7613 applies the PRECIS UsernameCaseMapped profile. Names that are
+// rejected by the profile fall back to a simple unicode lowercase.
+func FoldRFC7613(name string) string {
+	key, err := precis.UsernameCaseMapped.CompareKey(name)
+	if err != nil {
+		return strings.ToLower(name)
+	}
+	return key
+}
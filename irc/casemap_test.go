@@ -0,0 +1,38 @@
+package irc
+
+import "testing"
+
+var foldTests = []struct {
+	mapping string
+	name    string
+	want    string
+}{
+	{CaseMappingASCII, "Batman", "batman"},
+	{CaseMappingASCII, "[Batman]", "[batman]"},
+	{CaseMappingASCII, "Ärger", "Ärger"},
+	{CaseMappingRFC1459, "Batman", "batman"},
+	{CaseMappingRFC1459, "[Bat\\man]~", "{bat|man}^"},
+	{CaseMappingRFC7613, "Batman", "batman"},
+	{CaseMappingRFC7613, "Ärger", "ärger"},
+}
+
+func TestFold(t *testing.T) {
+	for _, test := range foldTests {
+		t.Run(test.mapping+" "+test.name, func(t *testing.T) {
+			fold, err := CaseMappingFold(test.mapping)
+			if err != nil {
+				t.Fatal(err)
+			}
+			have := fold(test.name)
+			if test.want != have {
+				t.Errorf("\n want: %v \n have: %v", test.want, have)
+			}
+		})
+	}
+}
+
+func TestFoldUnknown(t *testing.T) {
+	if _, err := CaseMappingFold("bogus"); err == nil {
+		t.Errorf("expected error")
+	}
+}
@@ -1,24 +1,45 @@
 package irc
 
 import (
+	"log"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 type Chan struct {
-	name    string
-	topic   string
-	status  string
-	nicks   *Nicks
-	clients map[UserID]*Client
-	modes   *ChanModes
-	mutex   sync.RWMutex
+	name      string
+	topic     string
+	topicTime time.Time
+	status    string
+	nicks     *Nicks
+	clients   map[UserID]*Client
+	modes     *ChanModes
+	invites   map[UserID]bool
+	mutex     sync.RWMutex
+
+	// Registered channels have a founder and save their state with persist
+	// when it changes. The access list is keyed by folded account name.
+	founder string
+	access  map[string]string
+	persist func(ChanReg) error
+
+	// A write that is waiting for the mutex to be released. Each write is
+	// numbered so that one overtaken by a later write is skipped.
+	pending   func()
+	version   int
+	saved     int
+	saveMutex sync.Mutex
 }
 
 const (
 	ChanPrefixNetwork = "#"
 	ChanPrefixLocal   = "&"
+	ChanTypes         = ChanPrefixNetwork + ChanPrefixLocal
+	ChanMaxLen        = 50
+	TopicMaxLen       = 390
 )
 
 func HasChanPrefix(chname string) bool {
@@ -28,12 +49,29 @@ func HasChanPrefix(chname string) bool {
 	return chname[0] == '#' || chname[0] == '&'
 }
 
+// ValidChanName checks the name against the grammar in RFC 2812. The name
+// must start with a channel prefix, be no longer than ChanMaxLen and
+// cannot contain NUL, BELL, CR, LF, space, comma or colon.
+func ValidChanName(chname string) error {
+	if !HasChanPrefix(chname) {
+		return NewError(ErrNoSuchChannel, chname)
+	}
+	if len(chname) == 1 || len(chname) > ChanMaxLen {
+		return NewError(ErrBadChanMask, chname)
+	}
+	if strings.ContainsAny(chname, "\x00\x07\r\n ,:") {
+		return NewError(ErrBadChanMask, chname)
+	}
+	return nil
+}
+
 func NewChan(name string, nicks *Nicks) *Chan {
 	c := &Chan{
 		name:    name,
 		nicks:   nicks,
 		clients: make(map[UserID]*Client),
 		modes:   NewChanModes(),
+		invites: make(map[UserID]bool),
 	}
 	c.modes.NoExternalMsgs = true
 	c.modes.TopicLock = true
@@ -49,6 +87,15 @@ func (c *Chan) Status() string {
 	return "="
 }
 
+// Prefix returns the mode prefix of the user and whether the user is a
+// member of the channel.
+func (c *Chan) Prefix(id UserID) (string, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	_, member := c.clients[id]
+	return c.modes.UserPrefix(id), member
+}
+
 func (c *Chan) Names() []string {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
@@ -71,7 +118,17 @@ func (c *Chan) Join(src *Client, key string) error {
 	if c.modes.Limit > 0 && len(c.clients) >= c.modes.Limit {
 		return NewError(ErrChannelIsFull, c.name)
 	}
-	if len(c.clients) == 0 {
+	if c.banned(src) {
+		return NewError(ErrBannedFromChan, c.name)
+	}
+	if c.modes.InviteOnly && !c.invites[src.User.ID] && !matchUser(c.modes.InviationMasks, src.User, c.nicks.fold) {
+		return NewError(ErrInviteOnlyChan, c.name)
+	}
+	delete(c.invites, src.User.ID)
+	switch {
+	case c.founder != "":
+		c.grantAccess(src)
+	case len(c.clients) == 0:
 		c.modes.Operators[src.User.ID] = true
 	}
 	c.clients[src.User.ID] = src
@@ -98,28 +155,116 @@ func (c *Chan) Part(src *Client, reason string) error {
 	return nil
 }
 
-func (c *Chan) PrivMsg(src *Client, text string) error {
+// Invite allows the target to join the channel even when it is invite
+// only. The invitation is consumed on the next join.
+func (c *Chan) Invite(src *Client, target *Client) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, member := c.clients[src.User.ID]; !member {
+		return NewError(ErrNotOnChannel, c.name)
+	}
+	if _, member := c.clients[target.User.ID]; member {
+		return NewError(ErrUserOnChannel, target.User.Nick, c.name)
+	}
+	if c.modes.InviteOnly && !c.modes.Operators[src.User.ID] {
+		return NewError(ErrChanOpPrivsNeeded, c.name)
+	}
+	c.invites[target.User.ID] = true
+	return nil
+}
+
+// Kick removes the member with the given nick from the channel. The client
+// that was removed is returned.
+func (c *Chan) Kick(src *Client, nick string, reason string) (*Client, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, member := c.clients[src.User.ID]; !member {
+		return nil, NewError(ErrNotOnChannel, c.name)
+	}
+	if !c.modes.Operators[src.User.ID] {
+		return nil, NewError(ErrChanOpPrivsNeeded, c.name)
+	}
+	user, exists := c.nicks.Get(nick)
+	if !exists {
+		return nil, NewError(ErrNoSuchNick, nick)
+	}
+	target, member := c.clients[user.ID]
+	if !member {
+		return nil, NewError(ErrUserNotInChannel, nick, c.name)
+	}
+	for _, cli := range c.clients {
+		cli.Relay(src.User, KickCmd, c.name, target.User.Nick, reason)
+	}
+	c.remove(target)
+	return target, nil
+}
+
+func (c *Chan) PrivMsg(src *Client, text string, tags map[string]string) error {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
 
+	if err := c.canSend(src); err != nil {
+		return err
+	}
+	c.relay(src, tags, PrivMsgCmd, c.name, text)
+	return nil
+}
+
+// Notice sends text to the channel like PrivMsg but silently drops the
+// message when it cannot be delivered.
+func (c *Chan) Notice(src *Client, text string, tags map[string]string) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if err := c.canSend(src); err != nil {
+		return
+	}
+	c.relay(src, tags, NoticeCmd, c.name, text)
+}
+
+// TagMsg sends the tags to members that negotiated message-tags.
+func (c *Chan) TagMsg(src *Client, tags map[string]string) error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if err := c.canSend(src); err != nil {
+		return err
+	}
+	c.relay(src, tags, TagMsgCmd, c.name)
+	return nil
+}
+
+func (c *Chan) canSend(src *Client) error {
 	if _, member := c.clients[src.User.ID]; c.modes.NoExternalMsgs && !member {
 		return NewError(ErrCannotSendToChan, c.name)
 	}
-	if c.modes.Moderated {
-		_, oper := c.modes.Operators[src.User.ID]
-		_, voiced := c.modes.Voiced[src.User.ID]
-		if !oper && !voiced {
-			return NewError(ErrCannotSendToChan, c.name)
-		}
+	_, oper := c.modes.Operators[src.User.ID]
+	_, voiced := c.modes.Voiced[src.User.ID]
+	if c.modes.Moderated && !oper && !voiced {
+		return NewError(ErrCannotSendToChan, c.name)
 	}
+	if c.banned(src) && !oper && !voiced {
+		return NewError(ErrCannotSendToChan, c.name)
+	}
+	return nil
+}
+
+// banned reports whether the client matches a ban mask without also
+// matching an exception.
+func (c *Chan) banned(src *Client) bool {
+	return matchUser(c.modes.Bans, src.User, c.nicks.fold) && !matchUser(c.modes.BanExceptions, src.User, c.nicks.fold)
+}
 
+// relay sends the message to every member except the source.
+func (c *Chan) relay(src *Client, tags map[string]string, cmd string, params ...string) {
 	for _, cli := range c.clients {
-		if cli.User.Nick == src.User.Nick {
+		if cli.User.ID == src.User.ID {
 			continue
 		}
-		cli.Relay(src.User, PrivMsgCmd, c.name, text)
+		cli.RelayTags(src.User, tags, cmd, params...)
 	}
-	return nil
 }
 
 func (c *Chan) Topic(src *Client) (string, error) {
@@ -135,7 +280,7 @@ func (c *Chan) Topic(src *Client) (string, error) {
 
 func (c *Chan) SetTopic(src *Client, topic string) error {
 	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	defer c.unlock()
 
 	if _, member := c.clients[src.User.ID]; !member {
 		return NewError(ErrNotOnChannel, c.name)
@@ -144,13 +289,44 @@ func (c *Chan) SetTopic(src *Client, topic string) error {
 		return NewError(ErrChanOpPrivsNeeded, c.name)
 	}
 
-	c.topic = topic
+	c.topic = Truncate(topic, TopicMaxLen)
+	c.topicTime = time.Now()
 	for _, client := range c.clients {
 		client.Relay(src.User, TopicCmd, c.name, c.topic)
 	}
+	c.save()
 	return nil
 }
 
+// Listing returns the number of members and the topic for a LIST reply.
+// Secret and private channels are only listed for members.
+func (c *Chan) Listing(src *Client) (int, string, time.Duration, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if !c.visible(src) {
+		return 0, "", 0, false
+	}
+	var age time.Duration
+	if c.topic != "" {
+		age = time.Since(c.topicTime)
+	}
+	return len(c.clients), c.topic, age, true
+}
+
+// Visible reports whether the channel can be seen by src. Secret and
+// private channels are only visible to members.
+func (c *Chan) Visible(src *Client) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.visible(src)
+}
+
+func (c *Chan) visible(src *Client) bool {
+	_, member := c.clients[src.User.ID]
+	return member || !(c.modes.Secret || c.modes.Private)
+}
+
 func (c *Chan) Members() []*Client {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
@@ -164,6 +340,18 @@ func (c *Chan) Members() []*Client {
 
 func (c *Chan) Mode(src *Client) ([]Mode, error) {
 	modes := make([]Mode, 0)
+	if c.modes.InviteOnly {
+		modes = append(modes, Mode{
+			Action: "+",
+			Char:   ChanModeInviteOnly,
+		})
+	}
+	if c.modes.Secret {
+		modes = append(modes, Mode{
+			Action: "+",
+			Char:   ChanModeSecret,
+		})
+	}
 	if c.modes.TopicLock {
 		modes = append(modes, Mode{
 			Action: "+",
@@ -189,10 +377,214 @@ func (c *Chan) Quit(src *Client) {
 	c.remove(src)
 }
 
+// Registered reports whether the channel is registered to an account.
+func (c *Chan) Registered() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.founder != ""
+}
+
+func (c *Chan) empty() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return len(c.clients) == 0
+}
+
+// restore sets the state of a registered channel that was saved before the
+// server started.
+func (c *Chan) restore(reg ChanReg, persist func(ChanReg) error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	modes := reg.Modes
+	modes.Operators = c.modes.Operators
+	modes.Voiced = c.modes.Voiced
+	c.modes = &modes
+	c.topic = reg.Topic
+	c.topicTime = reg.TopicTime
+	c.founder = reg.Founder
+	c.access = reg.Access
+	c.persist = persist
+}
+
+func (c *Chan) register(src *Client, persist func(ChanReg) error) error {
+	c.mutex.Lock()
+	defer c.unlock()
+	if !c.modes.Operators[src.User.ID] {
+		return NewError(ErrChanOpPrivsNeeded, c.name)
+	}
+	if c.founder != "" {
+		return errChanRegistered
+	}
+	c.founder = src.User.Account
+	c.access = make(map[string]string)
+	c.persist = persist
+	c.save()
+	return nil
+}
+
+// drop removes the registration. The saved state is deleted with remove.
+func (c *Chan) drop(src *Client, remove func() error) error {
+	c.mutex.Lock()
+	defer c.unlock()
+	if err := c.checkFounder(src); err != nil {
+		return err
+	}
+	c.founder = ""
+	c.access = nil
+	c.persist = nil
+	c.queue(remove)
+	return nil
+}
+
+func (c *Chan) setAccess(src *Client, account string, mode string) error {
+	c.mutex.Lock()
+	defer c.unlock()
+	if err := c.checkFounder(src); err != nil {
+		return err
+	}
+	if mode == "" {
+		delete(c.access, c.nicks.fold(account))
+	} else {
+		c.access[c.nicks.fold(account)] = mode
+	}
+	c.save()
+	return nil
+}
+
+// accessList can be seen by the founder and channel operators.
+func (c *Chan) accessList(src *Client) (map[string]string, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if c.founder == "" {
+		return nil, errChanNotRegistered
+	}
+	if !c.modes.Operators[src.User.ID] && c.checkFounder(src) != nil {
+		return nil, NewError(ErrChanOpPrivsNeeded, c.name)
+	}
+	access := make(map[string]string, len(c.access))
+	for account, mode := range c.access {
+		access[account] = mode
+	}
+	return access, nil
+}
+
+func (c *Chan) checkFounder(src *Client) error {
+	if c.founder == "" {
+		return errChanNotRegistered
+	}
+	if src.User.Account == "" || c.nicks.fold(src.User.Account) != c.nicks.fold(c.founder) {
+		return errNotFounder
+	}
+	return nil
+}
+
+// grantAccess gives operator or voice status to a joining member of a
+// registered channel if the account of the member is in the access list.
+func (c *Chan) grantAccess(src *Client) {
+	account := c.nicks.fold(src.User.Account)
+	if account == "" {
+		return
+	}
+	switch {
+	case account == c.nicks.fold(c.founder), c.access[account] == ChanModeOper:
+		c.modes.Operators[src.User.ID] = true
+	case c.access[account] == ChanModeVoice:
+		c.modes.Voiced[src.User.ID] = true
+	}
+}
+
+// LogIn gives a member that has just logged in the status granted to its
+// account and tells the channel about it.
+func (c *Chan) LogIn(origin Origin, src *Client) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.founder == "" {
+		return
+	}
+	oper, voiced := c.modes.Operators[src.User.ID], c.modes.Voiced[src.User.ID]
+	c.grantAccess(src)
+	mode := ""
+	switch {
+	case !oper && c.modes.Operators[src.User.ID]:
+		mode = "+" + ChanModeOper
+	case !voiced && c.modes.Voiced[src.User.ID]:
+		mode = "+" + ChanModeVoice
+	default:
+		return
+	}
+	for _, cli := range c.clients {
+		cli.SendMessage(Message{
+			Prefix:   origin.Origin(),
+			Cmd:      ModeCmd,
+			Params:   []string{c.name, mode, src.User.Nick},
+			NoSpaces: true,
+		})
+	}
+}
+
+// save passes the state of a registered channel to persist once the
+// channel mutex is released. The mutex must be held by the caller, who
+// releases it with unlock.
+func (c *Chan) save() {
+	if c.persist == nil {
+		return
+	}
+	m := *c.modes
+	m.Bans = append([]string(nil), m.Bans...)
+	m.BanExceptions = append([]string(nil), m.BanExceptions...)
+	m.InviationMasks = append([]string(nil), m.InviationMasks...)
+	m.Operators = nil
+	m.Voiced = nil
+	access := make(map[string]string, len(c.access))
+	for account, mode := range c.access {
+		access[account] = mode
+	}
+	reg := ChanReg{
+		Name:      c.name,
+		Founder:   c.founder,
+		Topic:     c.topic,
+		TopicTime: c.topicTime,
+		Modes:     m,
+		Access:    access,
+	}
+	persist := c.persist
+	c.queue(func() error { return persist(reg) })
+}
+
+// queue sets the write to run when unlock releases the channel mutex. The
+// mutex must be held by the caller.
+func (c *Chan) queue(write func() error) {
+	c.version++
+	version := c.version
+	c.pending = func() {
+		c.saveMutex.Lock()
+		defer c.saveMutex.Unlock()
+		if version <= c.saved {
+			return
+		}
+		c.saved = version
+		if err := write(); err != nil {
+			log.Printf("unable to save channel %v: %v", c.name, err)
+		}
+	}
+}
+
+// unlock releases the channel mutex and then runs the pending write, if
+// any, so that the database is not written while the mutex is held.
+func (c *Chan) unlock() {
+	write := c.pending
+	c.pending = nil
+	c.mutex.Unlock()
+	if write != nil {
+		write()
+	}
+}
+
 func (c *Chan) remove(src *Client) {
 	delete(c.modes.Operators, src.User.ID)
 	delete(c.modes.Voiced, src.User.ID)
 	delete(c.clients, src.User.ID)
+	delete(c.invites, src.User.ID)
 }
 
 type ChanModeCmds struct {
@@ -211,11 +603,41 @@ func newChanModeCmds(c *Chan, src *Client) ChanModeCmds {
 	return cmd
 }
 
-func (cmd *ChanModeCmds) Ban(action string, who string) error {
+func (cmd *ChanModeCmds) Ban(action string, mask string) error {
+	return cmd.list(ChanModeBan, &cmd.c.modes.Bans, action, mask)
+}
+
+func (cmd *ChanModeCmds) BanException(action string, mask string) error {
+	return cmd.list(ChanModeBanException, &cmd.c.modes.BanExceptions, action, mask)
+}
+
+func (cmd *ChanModeCmds) InvitationMask(action string, mask string) error {
+	return cmd.list(ChanModeInvitationMask, &cmd.c.modes.InviationMasks, action, mask)
+}
+
+func (cmd *ChanModeCmds) InviteOnly(action string) error {
+	c := cmd.c
+
+	// Is the action valid?
+	if action != "+" && action != "-" {
+		return nil
+	}
+	set := action == "+"
+
+	// Is the user sending the command an operator?
+	if !c.modes.Operators[cmd.src.User.ID] {
+		return NewError(ErrChanOpPrivsNeeded, c.name)
+	}
+
+	// Is a mode change needed?
+	if set == c.modes.InviteOnly {
+		return nil
+	}
+
+	c.modes.InviteOnly = set
 	cmd.changes = append(cmd.changes, Mode{
 		Action: action,
-		Char:   ChanModeBan,
-		List:   []string{},
+		Char:   ChanModeInviteOnly,
 	})
 	return nil
 }
@@ -387,6 +809,33 @@ func (cmd *ChanModeCmds) Oper(action string, name string) error {
 	return nil
 }
 
+func (cmd *ChanModeCmds) Secret(action string) error {
+	c := cmd.c
+
+	// Is the action valid?
+	if action != "+" && action != "-" {
+		return nil
+	}
+	set := action == "+"
+
+	// Is the user sending the command an operator?
+	if !c.modes.Operators[cmd.src.User.ID] {
+		return NewError(ErrChanOpPrivsNeeded, c.name)
+	}
+
+	// Is a mode change needed?
+	if set == c.modes.Secret {
+		return nil
+	}
+
+	c.modes.Secret = set
+	cmd.changes = append(cmd.changes, Mode{
+		Action: action,
+		Char:   ChanModeSecret,
+	})
+	return nil
+}
+
 func (cmd *ChanModeCmds) TopicLock(action string) error {
 	c := cmd.c
 
@@ -459,6 +908,64 @@ func (cmd *ChanModeCmds) Voice(action string, name string) error {
 	return nil
 }
 
+// list adds or removes a mask from a list mode. When no mask is given, the
+// current contents of the list are returned to the user instead.
+func (cmd *ChanModeCmds) list(char string, masks *[]string, action string, mask string) error {
+	c := cmd.c
+
+	// Is this a query for the current list?
+	if mask == "" {
+		if action == "-" {
+			return nil
+		}
+		list := make([]string, len(*masks))
+		copy(list, *masks)
+		cmd.changes = append(cmd.changes, Mode{
+			Action: action,
+			Char:   char,
+			List:   list,
+		})
+		return nil
+	}
+
+	// Is the action valid?
+	if action != "+" && action != "-" {
+		return nil
+	}
+	set := action == "+"
+
+	// Is the user sending the command an operator?
+	if !c.modes.Operators[cmd.src.User.ID] {
+		return NewError(ErrChanOpPrivsNeeded, c.name)
+	}
+
+	// Is a mode change needed?
+	mask = Truncate(NormalizeMask(mask), MaskMaxLen)
+	i := indexMask(*masks, mask, c.nicks.fold)
+	if set == (i >= 0) {
+		return nil
+	}
+
+	// Is there room in the list?
+	total := len(c.modes.Bans) + len(c.modes.BanExceptions) + len(c.modes.InviationMasks)
+	if set && total >= ChanListMaxLen {
+		return NewError(ErrBanListFull, c.name, char)
+	}
+
+	if set {
+		*masks = append(*masks, mask)
+	} else {
+		mask = (*masks)[i]
+		*masks = append((*masks)[:i], (*masks)[i+1:]...)
+	}
+	cmd.changes = append(cmd.changes, Mode{
+		Action: action,
+		Char:   char,
+		Param:  mask,
+	})
+	return nil
+}
+
 func (cmd ChanModeCmds) Done() {
 	if len(cmd.changes) > 0 {
 		for _, cli := range cmd.c.clients {
@@ -478,9 +985,28 @@ func (cmd ChanModeCmds) Done() {
 		for _, mode := range cmd.changes {
 			switch {
 			case mode.Char == ChanModeBan && mode.List != nil:
+				for _, mask := range mode.List {
+					cmd.src.Reply(RplBanList, cmd.c.name, mask)
+				}
 				cmd.src.Reply(RplEndOfBanList, cmd.c.name)
+			case mode.Char == ChanModeBanException && mode.List != nil:
+				for _, mask := range mode.List {
+					cmd.src.Reply(RplExceptList, cmd.c.name, mask)
+				}
+				cmd.src.Reply(RplEndOfExceptList, cmd.c.name)
+			case mode.Char == ChanModeInvitationMask && mode.List != nil:
+				for _, mask := range mode.List {
+					cmd.src.Reply(RplInviteList, cmd.c.name, mask)
+				}
+				cmd.src.Reply(RplEndOfInviteList, cmd.c.name)
+			}
+		}
+		for _, mode := range cmd.changes {
+			if mode.List == nil {
+				cmd.c.save()
+				break
 			}
 		}
 	}
-	cmd.c.mutex.Unlock()
+	cmd.c.unlock()
 }
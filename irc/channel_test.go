@@ -0,0 +1,57 @@
+package irc
+
+import (
+	"strings"
+	"testing"
+)
+
+var validChanNameTests = []struct {
+	name string
+	want string
+}{
+	{"#gotham", ""},
+	{"&gotham", ""},
+	{"#gotham-city", ""},
+	{"gotham", ErrNoSuchChannel},
+	{"", ErrNoSuchChannel},
+	{"#", ErrBadChanMask},
+	{"#gotham city", ErrBadChanMask},
+	{"#gotham,#batcave", ErrBadChanMask},
+	{"#gotham\x07", ErrBadChanMask},
+	{"#gotham:city", ErrBadChanMask},
+	{"#" + strings.Repeat("a", ChanMaxLen-1), ""},
+	{"#" + strings.Repeat("a", ChanMaxLen), ErrBadChanMask},
+}
+
+func TestValidChanName(t *testing.T) {
+	for _, test := range validChanNameTests {
+		t.Run(test.name, func(t *testing.T) {
+			have := ""
+			if err := ValidChanName(test.name); err != nil {
+				have = err.(*Error).Numeric
+			}
+			if test.want != have {
+				t.Errorf("\n want: %v \n have: %v", test.want, have)
+			}
+		})
+	}
+}
+
+func TestQuitClearsInvite(t *testing.T) {
+	ch := NewChan("#gotham", NewNicks())
+	batman := newTestClient(1000)
+	batman.User.ID = 1
+	robin := newTestClient(1000)
+	robin.User = &User{ID: 2, Nick: "Robin"}
+
+	if err := ch.Join(batman, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Invite(batman, robin); err != nil {
+		t.Fatal(err)
+	}
+	ch.Quit(robin)
+	if len(ch.invites) != 0 {
+		t.Errorf("invites not cleared: %v", ch.invites)
+	}
+}
@@ -0,0 +1,242 @@
+package irc
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+var (
+	errChanRegistered    = errors.New("channel already registered")
+	errChanNotRegistered = errors.New("channel not registered")
+	errNotFounder        = errors.New("not the channel founder")
+)
+
+// ChanReg is the part of a registered channel that is kept in
+// BucketChannels. Operator and voice status is granted to accounts in the
+// access list, and to the founder, when they join.
+type ChanReg struct {
+	Name      string
+	Founder   string
+	Topic     string
+	TopicTime time.Time
+	Modes     ChanModes
+	Access    map[string]string
+}
+
+// Each registered channel is a bucket in BucketChannels keyed by the
+// folded channel name. The access list is a nested bucket of account names
+// and the mode granted to each.
+func putChanReg(tx *bolt.Tx, key string, reg ChanReg) error {
+	channels := tx.Bucket(BucketChannels)
+	if channels.Bucket([]byte(key)) != nil {
+		if err := channels.DeleteBucket([]byte(key)); err != nil {
+			return err
+		}
+	}
+	ch, err := channels.CreateBucket([]byte(key))
+	if err != nil {
+		return err
+	}
+	m := reg.Modes
+	ch.Put(ChannelName, []byte(reg.Name))
+	ch.Put(ChannelFounder, []byte(reg.Founder))
+	ch.Put(ChannelTopic, []byte(reg.Topic))
+	if !reg.TopicTime.IsZero() {
+		ch.Put(ChannelTopicTime, []byte(strconv.FormatInt(reg.TopicTime.Unix(), 10)))
+	}
+	ch.Put(ChannelModes, []byte(chanRegFlags(m)))
+	ch.Put(ChannelKey, []byte(m.Key))
+	ch.Put(ChannelLimit, []byte(strconv.Itoa(m.Limit)))
+	ch.Put(ChannelBans, []byte(strings.Join(m.Bans, "\n")))
+	ch.Put(ChannelExceptions, []byte(strings.Join(m.BanExceptions, "\n")))
+	ch.Put(ChannelInvex, []byte(strings.Join(m.InviationMasks, "\n")))
+	access, err := ch.CreateBucket(ChannelAccess)
+	if err != nil {
+		return err
+	}
+	for account, mode := range reg.Access {
+		access.Put([]byte(account), []byte(mode))
+	}
+	return nil
+}
+
+func getChanReg(ch *bolt.Bucket) ChanReg {
+	reg := ChanReg{
+		Name:    string(ch.Get(ChannelName)),
+		Founder: string(ch.Get(ChannelFounder)),
+		Topic:   string(ch.Get(ChannelTopic)),
+		Modes:   *NewChanModes(),
+		Access:  make(map[string]string),
+	}
+	if unix, err := strconv.ParseInt(string(ch.Get(ChannelTopicTime)), 10, 64); err == nil {
+		reg.TopicTime = time.Unix(unix, 0)
+	}
+	m := &reg.Modes
+	for _, char := range string(ch.Get(ChannelModes)) {
+		switch string(char) {
+		case ChanModeInviteOnly:
+			m.InviteOnly = true
+		case ChanModeModerated:
+			m.Moderated = true
+		case ChanModeNoExternalMsgs:
+			m.NoExternalMsgs = true
+		case ChanModePrivate:
+			m.Private = true
+		case ChanModeSecret:
+			m.Secret = true
+		case ChanModeTopicLock:
+			m.TopicLock = true
+		}
+	}
+	m.Key = string(ch.Get(ChannelKey))
+	m.Limit, _ = strconv.Atoi(string(ch.Get(ChannelLimit)))
+	m.Bans = splitMasks(ch.Get(ChannelBans))
+	m.BanExceptions = splitMasks(ch.Get(ChannelExceptions))
+	m.InviationMasks = splitMasks(ch.Get(ChannelInvex))
+	if access := ch.Bucket(ChannelAccess); access != nil {
+		access.ForEach(func(account []byte, mode []byte) error {
+			reg.Access[string(account)] = string(mode)
+			return nil
+		})
+	}
+	return reg
+}
+
+func chanRegFlags(m ChanModes) string {
+	flags := []struct {
+		set  bool
+		char string
+	}{
+		{m.InviteOnly, ChanModeInviteOnly},
+		{m.Moderated, ChanModeModerated},
+		{m.NoExternalMsgs, ChanModeNoExternalMsgs},
+		{m.Private, ChanModePrivate},
+		{m.Secret, ChanModeSecret},
+		{m.TopicLock, ChanModeTopicLock},
+	}
+	chars := ""
+	for _, flag := range flags {
+		if flag.set {
+			chars += flag.char
+		}
+	}
+	return chars
+}
+
+func splitMasks(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	return strings.Split(string(b), "\n")
+}
+
+// restoreChans creates a channel for each registered channel. It is called
+// when the server starts.
+func (s *Service) restoreChans() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.db.View(func(tx *bolt.Tx) error {
+		channels := tx.Bucket(BucketChannels)
+		return channels.ForEach(func(key []byte, _ []byte) error {
+			bucket := channels.Bucket(key)
+			if bucket == nil {
+				return nil
+			}
+			reg := getChanReg(bucket)
+			ch := NewChan(reg.Name, s.nicks)
+			ch.restore(reg, s.persist(string(key)))
+			s.chans[string(key)] = ch
+			return nil
+		})
+	})
+}
+
+// persist returns the function used by a registered channel to save its
+// state.
+func (s *Service) persist(key string) func(ChanReg) error {
+	return func(reg ChanReg) error {
+		return s.db.Update(func(tx *bolt.Tx) error {
+			return putChanReg(tx, key, reg)
+		})
+	}
+}
+
+// RegisterChan registers the channel to the account of the client, who must
+// be a channel operator.
+func (s *Service) RegisterChan(src *Client, name string) error {
+	ch, err := s.Chan(name)
+	if err != nil {
+		return NewError(ErrNoSuchChannel, name)
+	}
+	if src.User.Account == "" {
+		return errNoSuchAccount
+	}
+	return ch.register(src, s.persist(s.fold(name)))
+}
+
+// DropChan removes the registration of a channel. Only the founder can drop
+// the channel.
+func (s *Service) DropChan(src *Client, name string) error {
+	ch, err := s.Chan(name)
+	if err != nil {
+		return NewError(ErrNoSuchChannel, name)
+	}
+	key := s.fold(name)
+	remove := func() error {
+		return s.db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket(BucketChannels).DeleteBucket([]byte(key))
+		})
+	}
+	if err := ch.drop(src, remove); err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.removeIfEmpty(ch)
+	return nil
+}
+
+// SetAccess grants operator or voice status to an account when it joins a
+// registered channel. An empty mode removes the account from the list.
+func (s *Service) SetAccess(src *Client, name string, account string, mode string) error {
+	ch, err := s.Chan(name)
+	if err != nil {
+		return err
+	}
+	if mode != "" && !s.accountExists(account) {
+		return errNoSuchAccount
+	}
+	return ch.setAccess(src, account, mode)
+}
+
+// AccessList returns the accounts in the access list of a registered
+// channel in sorted order with the mode granted to each.
+func (s *Service) AccessList(src *Client, name string) ([][2]string, error) {
+	ch, err := s.Chan(name)
+	if err != nil {
+		return nil, err
+	}
+	access, err := ch.accessList(src)
+	if err != nil {
+		return nil, err
+	}
+	list := make([][2]string, 0, len(access))
+	for account, mode := range access {
+		list = append(list, [2]string{account, mode})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i][0] < list[j][0] })
+	return list, nil
+}
+
+// removeIfEmpty forgets a channel without members unless it is registered.
+// The service mutex must be held by the caller.
+func (s *Service) removeIfEmpty(ch *Chan) {
+	if ch.empty() && !ch.Registered() {
+		delete(s.chans, s.fold(ch.Name()))
+	}
+}
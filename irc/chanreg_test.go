@@ -0,0 +1,77 @@
+package irc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestChanRegRoundTrip(t *testing.T) {
+	s := newTestService(t)
+	modes := ChanModes{
+		Bans:           []string{"Joker!*@*", "*!*@arkham"},
+		BanExceptions:  []string{"Robin!*@*"},
+		InviationMasks: []string{"*!*@wayne.com"},
+		InviteOnly:     true,
+		Key:            "batcave",
+		Limit:          10,
+		TopicLock:      true,
+		NoExternalMsgs: true,
+		Operators:      map[UserID]bool{},
+		Voiced:         map[UserID]bool{},
+	}
+	want := ChanReg{
+		Name:      "#Gotham",
+		Founder:   "Batman",
+		Topic:     "Protect the city",
+		TopicTime: time.Unix(1234567890, 0),
+		Modes:     modes,
+		Access:    map[string]string{"robin": ChanModeVoice, "alfred": ChanModeOper},
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return putChanReg(tx, "#gotham", want)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var have ChanReg
+	s.db.View(func(tx *bolt.Tx) error {
+		have = getChanReg(tx.Bucket(BucketChannels).Bucket([]byte("#gotham")))
+		return nil
+	})
+	if !reflect.DeepEqual(want, have) {
+		t.Fatalf("\n want: %+v \n have: %+v", want, have)
+	}
+}
+
+func TestChanSaveUnlocked(t *testing.T) {
+	ch := NewChan("#gotham", NewNicks())
+	c := newTestClient(1000)
+	c.User = &User{ID: 1, Nick: "Batman", Account: "Batman"}
+	if err := ch.Join(c, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	saves := 0
+	persist := func(reg ChanReg) error {
+		if !ch.mutex.TryLock() {
+			t.Errorf("channel saved while locked")
+		} else {
+			ch.mutex.Unlock()
+		}
+		saves++
+		return nil
+	}
+	if err := ch.register(c, persist); err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.SetTopic(c, "Protect the city"); err != nil {
+		t.Fatal(err)
+	}
+	if saves != 2 {
+		t.Errorf("\n want: 2 \n have: %v", saves)
+	}
+}
@@ -1,13 +1,20 @@
 package irc
 
 import (
+	"crypto/tls"
 	"errors"
+	"fmt"
 	"net"
+	"sort"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/blackchip-org/chatty/internal/security"
 )
 
+var errSendQueueFull = errors.New("send queue full")
+
 type Client struct {
 	User       *User
 	ServerName string
@@ -15,22 +22,70 @@ type Client struct {
 	mutex      sync.RWMutex
 	err        error
 	registered bool
-	sendq      chan Message
+	quit       bool
+	secure     bool
+	signon     time.Time
+	active     time.Time
+	recv       time.Time
+
+	// Lines waiting for the writer. The length of the queue is limited by
+	// the number of bytes it holds rather than the number of lines.
+	sendq    []string
+	sendqLen int
+	sendqMax int
+	wake     chan struct{}
+	closed   bool
+	closing  string
+	stats    linkStats
 
 	password string
 	chans    map[string]*Chan
+	// Channels with a pending invitation for the client, keyed like chans
+	// and guarded by the service mutex.
+	invited map[string]*Chan
+
+	// Capabilities enabled by the client. Registration is held until
+	// CAP END once negotiation has started.
+	caps         map[string]bool
+	capVersion   int
+	capNegotiate bool
+
+	// SASL exchange in progress with AUTHENTICATE
+	saslMech  string
+	saslData  strings.Builder
+	saslFails int
+
+	// Changes the nick if the client does not log in to the account of
+	// the same name. Guarded by the service mutex.
+	nickTimer *time.Timer
+
+	// Work from other goroutines that changes the client, such as a forced
+	// nick change, is run by the goroutine handling the client's commands.
+	tasks    []func()
+	taskWake chan struct{}
 }
 
 func newClientUser(conn net.Conn, server *Server) *Client {
 	host := server.Name
 	realHost := hostnameFromAddr(conn.RemoteAddr().String())
 
+	_, secure := conn.(*tls.Conn)
+	now := time.Now()
+
 	c := &Client{
 		User:       newUser(host, realHost),
 		ServerName: server.Name,
 		conn:       conn,
-		sendq:      make(chan Message, queueMaxLen),
+		secure:     secure,
+		sendqMax:   server.SendQ,
+		wake:       make(chan struct{}, 1),
+		taskWake:   make(chan struct{}, 1),
+		signon:     now,
+		active:     now,
+		recv:       now,
 		chans:      make(map[string]*Chan),
+		invited:    make(map[string]*Chan),
+		caps:       make(map[string]bool),
 	}
 	conn.SetDeadline(time.Now().Add(server.RegistrationDeadline))
 	return c
@@ -47,9 +102,13 @@ func (c *Client) Reply(cmd string, params ...string) *Client {
 	if exists {
 		params = append(params, text)
 	}
+	nick := "*"
+	if c.User.Nick != "" {
+		nick = c.User.Nick
+	}
 	m := Message{
 		Prefix: c.ServerName,
-		Target: c.User.Nick,
+		Target: nick,
 		Cmd:    cmd,
 		Params: params,
 	}
@@ -57,8 +116,56 @@ func (c *Client) Reply(cmd string, params ...string) *Client {
 	return c
 }
 
+// ReplyWords sends the words as the final parameter of a reply. The words
+// are split across as many replies as needed to keep each line within
+// MessageMaxLen.
+func (c *Client) ReplyWords(cmd string, params []string, words []string) *Client {
+	m := Message{
+		Prefix: c.ServerName,
+		Target: c.User.Nick,
+		Cmd:    cmd,
+		Params: append(params[:len(params):len(params)], ""),
+	}
+	// Allow for the trailing CR-LF
+	max := MessageMaxLen - len(m.Encode()) - 2
+	for _, line := range SplitWords(words, max) {
+		c.Reply(cmd, append(params[:len(params):len(params)], line)...)
+	}
+	return c
+}
+
 func (c *Client) Relay(o Origin, cmd string, params ...string) *Client {
-	m := Message{Prefix: o.Origin(), Cmd: cmd, Params: params}
+	return c.RelayTags(o, nil, cmd, params...)
+}
+
+// RelayTags is like Relay but includes the tags if the client negotiated
+// message-tags. A TAGMSG is not sent at all to clients that did not.
+// Clients that negotiated account-tag are sent the account of the user
+// the message is from.
+func (c *Client) RelayTags(o Origin, tags map[string]string, cmd string, params ...string) *Client {
+	if !c.HasCap(CapMessageTags) {
+		if cmd == TagMsgCmd {
+			return c
+		}
+		tags = nil
+	}
+	if account := originAccount(o); account != "" && c.HasCap(CapAccountTag) {
+		withAccount := map[string]string{"account": account}
+		for k, v := range tags {
+			withAccount[k] = v
+		}
+		tags = withAccount
+	}
+	m := Message{Tags: tags, Prefix: o.Origin(), Cmd: cmd, Params: params}
+	c.SendMessage(m)
+	return c
+}
+
+// Fail sends a standard FAIL reply. The last parameter is the description.
+// https://ircv3.net/specs/extensions/standard-replies
+func (c *Client) Fail(cmd string, code string, params ...string) *Client {
+	params = append([]string{cmd, code}, params...)
+	m := Message{Prefix: c.ServerName, Cmd: FailCmd, Params: params}
 	c.SendMessage(m)
 	return c
 }
@@ -91,25 +198,246 @@ func (c *Client) SendError(err error) *Client {
 
 func (c *Client) SetRegistered() {
 	c.registered = true
+	c.signon = time.Now()
 	c.conn.SetDeadline(time.Time{})
 }
 
+// SendMessage queues the message for the writer. If the queue would grow
+// beyond its limit, queued messages are discarded and the connection is
+// closed.
 func (c *Client) SendMessage(m Message) {
-	if c.err != nil {
+	line := m.Encode()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.err != nil || c.closed {
 		return
 	}
-	select {
-	case c.sendq <- m:
+	if c.sendqLen+len(line) > c.sendqMax {
+		c.err = errSendQueueFull
+		c.sendq = nil
+		c.sendqLen = 0
+		c.closeLocked("SendQ exceeded")
 		return
+	}
+	c.sendq = append(c.sendq, line)
+	c.sendqLen += len(line)
+	c.notify()
+}
+
+// SendQ returns the number of bytes waiting to be sent to the client.
+func (c *Client) SendQ() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.sendqLen
+}
+
+// close sends an ERROR with the reason and has the writer close the
+// connection once everything queued before it has been sent.
+func (c *Client) close(reason string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.closeLocked(reason)
+}
+
+func (c *Client) closeLocked(reason string) {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.closing = reason
+	text := "Closing Link"
+	if reason != "" {
+		text = fmt.Sprintf("Closing Link (%v)", reason)
+	}
+	m := Message{Cmd: ErrorCmd, Params: []string{text}}
+	c.sendq = append(c.sendq, m.Encode())
+	c.notify()
+}
+
+// queue runs fn on the goroutine that handles the commands sent by the
+// client.
+func (c *Client) queue(fn func()) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.tasks = append(c.tasks, fn)
+	select {
+	case c.taskWake <- struct{}{}:
 	default:
-		c.err = errors.New("send queue full")
 	}
 }
 
+// dequeueTasks removes all functions waiting to be run by queue.
+func (c *Client) dequeueTasks() []func() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	tasks := c.tasks
+	c.tasks = nil
+	return tasks
+}
+
+// closeReason returns the reason given when the connection was closed by
+// the server or by a QUIT.
+func (c *Client) closeReason() (string, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.closing, c.closed
+}
+
+func (c *Client) notify() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// dequeue removes all lines waiting in the send queue. The second value is
+// true when the connection should be closed after the lines are sent.
+func (c *Client) dequeue() ([]string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	lines := c.sendq
+	c.sendq = nil
+	c.sendqLen = 0
+	return lines, c.closed
+}
+
+// HasCap reports whether the client has enabled the named capability.
+func (c *Client) HasCap(name string) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.caps[name]
+}
+
+func originAccount(o Origin) string {
+	switch u := o.(type) {
+	case *User:
+		return u.Account
+	case User:
+		return u.Account
+	}
+	return ""
+}
+
+// CertFP returns the fingerprint of the TLS client certificate, or an empty
+// string if the client did not send one.
+func (c *Client) CertFP() string {
+	tconn, ok := c.conn.(*tls.Conn)
+	if !ok {
+		return ""
+	}
+	certs := tconn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return ""
+	}
+	return security.Fingerprint(certs[0].Raw)
+}
+
+// EnabledCaps returns the names of the capabilities enabled by the client
+// in sorted order.
+func (c *Client) EnabledCaps() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	names := make([]string, 0, len(c.caps))
+	for name := range c.caps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// setCapVersion records the CAP version of the client. Version 302 enables
+// cap-notify.
+func (c *Client) setCapVersion(version int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.capVersion = version
+	if version >= CapVersion {
+		c.caps[CapCapNotify] = true
+	}
+}
+
+// capNotify returns the CAP version of the client and whether it wants to
+// be told of changes to the capabilities.
+func (c *Client) capNotify() (int, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.capVersion, c.caps[CapCapNotify]
+}
+
+func (c *Client) setCap(name string, enabled bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if enabled {
+		c.caps[name] = true
+	} else {
+		delete(c.caps, name)
+	}
+}
+
+// Idle returns the amount of time since the client last sent a command
+// other than a PING or PONG.
+func (c *Client) Idle() time.Duration {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return time.Since(c.active)
+}
+
+func (c *Client) markActive() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.active = time.Now()
+}
+
+func (c *Client) markReceived(n int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.recv = time.Now()
+	c.stats.RecvMsgs++
+	c.stats.RecvBytes += n
+}
+
+func (c *Client) markSent(n int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.stats.SentMsgs++
+	c.stats.SentBytes += n
+}
+
+// linkStats counts the traffic on the connection for STATS l.
+type linkStats struct {
+	SentMsgs  int
+	SentBytes int
+	RecvMsgs  int
+	RecvBytes int
+}
+
+func (c *Client) linkStats() linkStats {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.stats
+}
+
+// received returns the time when the last line was read from the client.
+func (c *Client) received() time.Time {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.recv
+}
+
 func (c *Client) Quit() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	c.err = Quit
 }
 
+// Err returns the error that ends the connection, if any. It is set by
+// Quit and when the send queue overflows.
+func (c *Client) Err() error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.err
+}
+
 func hostnameFromAddr(addr string) string {
 	i := strings.LastIndex(addr, ":")
 	ipAddr := addr[:i]
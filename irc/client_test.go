@@ -0,0 +1,88 @@
+package irc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newTestClient(sendqMax int) *Client {
+	return &Client{
+		User:       &User{Nick: "Batman"},
+		ServerName: "irc.localhost",
+		sendqMax:   sendqMax,
+		wake:       make(chan struct{}, 1),
+		taskWake:   make(chan struct{}, 1),
+		chans:      make(map[string]*Chan),
+		invited:    make(map[string]*Chan),
+	}
+}
+
+func TestSendQ(t *testing.T) {
+	c := newTestClient(100)
+	c.Send(PingCmd, "irc.localhost")
+	want := len(":irc.localhost PING :irc.localhost")
+	have := c.SendQ()
+	if want != have {
+		t.Fatalf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestSendQExceeded(t *testing.T) {
+	c := newTestClient(100)
+	for i := 0; i < 5; i++ {
+		c.Send(PingCmd, "irc.localhost")
+	}
+	if c.err != errSendQueueFull {
+		t.Fatalf("expected send queue full error")
+	}
+	lines, closing := c.dequeue()
+	want := []string{"ERROR :Closing Link (SendQ exceeded)"}
+	if !reflect.DeepEqual(want, lines) {
+		t.Fatalf("\n want: %v \n have: %v", want, lines)
+	}
+	if !closing {
+		t.Fatalf("expected connection to be closing")
+	}
+}
+
+func TestClose(t *testing.T) {
+	c := newTestClient(100)
+	c.Send(PingCmd, "irc.localhost")
+	c.close("Ping timeout")
+	c.close("Connection reset")
+	c.Send(PingCmd, "irc.localhost")
+
+	lines, _ := c.dequeue()
+	want := []string{
+		":irc.localhost PING :irc.localhost",
+		"ERROR :Closing Link (Ping timeout)",
+	}
+	if !reflect.DeepEqual(want, lines) {
+		t.Fatalf("\n want: %v \n have: %v", want, lines)
+	}
+	reason, _ := c.closeReason()
+	if reason != "Ping timeout" {
+		t.Fatalf("\n want: %v \n have: %v", "Ping timeout", reason)
+	}
+}
+
+func TestQueue(t *testing.T) {
+	c := newTestClient(100)
+	ran := 0
+	c.queue(func() { ran++ })
+	c.queue(func() { ran++ })
+	select {
+	case <-c.taskWake:
+	default:
+		t.Fatalf("expected wake")
+	}
+	for _, task := range c.dequeueTasks() {
+		task()
+	}
+	if ran != 2 {
+		t.Fatalf("\n want: %v \n have: %v", 2, ran)
+	}
+	if tasks := c.dequeueTasks(); len(tasks) != 0 {
+		t.Fatalf("\n want: %v \n have: %v", 0, len(tasks))
+	}
+}
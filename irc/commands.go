@@ -1,22 +1,53 @@
 package irc
 
 const (
-	CapCmd     = "CAP"
-	CapLsCmd   = "LS"
-	CapReqCmd  = "REQ"
-	CapEndCmd  = "END"
-	JoinCmd    = "JOIN"
-	ModeCmd    = "MODE"
-	NamesCmd   = "NAMES"
-	NickCmd    = "NICK"
-	OperCmd    = "OPER"
-	PartCmd    = "PART"
-	PassCmd    = "PASS"
-	PingCmd    = "PING"
-	PongCmd    = "PONG"
-	PrivMsgCmd = "PRIVMSG"
-	TopicCmd   = "TOPIC"
-	UserCmd    = "USER"
-	QuitCmd    = "QUIT"
-	WhoCmd     = "WHO"
+	AccessCmd       = "ACCESS"
+	AdminCmd        = "ADMIN"
+	AuthenticateCmd = "AUTHENTICATE"
+	AwayCmd         = "AWAY"
+	CapCmd          = "CAP"
+	CapLsCmd        = "LS"
+	CapReqCmd       = "REQ"
+	CapEndCmd       = "END"
+	CapAckCmd       = "ACK"
+	CapNakCmd       = "NAK"
+	CapListCmd      = "LIST"
+	CapNewCmd       = "NEW"
+	CapDelCmd       = "DEL"
+	CertFPCmd       = "CERTFP"
+	DropChanCmd     = "DROPCHAN"
+	ErrorCmd        = "ERROR"
+	FailCmd         = "FAIL"
+	InfoCmd         = "INFO"
+	InviteCmd       = "INVITE"
+	IsonCmd         = "ISON"
+	JoinCmd         = "JOIN"
+	KickCmd         = "KICK"
+	ListCmd         = "LIST"
+	LusersCmd       = "LUSERS"
+	ModeCmd         = "MODE"
+	MotdCmd         = "MOTD"
+	NamesCmd        = "NAMES"
+	NickCmd         = "NICK"
+	NoticeCmd       = "NOTICE"
+	OperCmd         = "OPER"
+	PartCmd         = "PART"
+	PassCmd         = "PASS"
+	PingCmd         = "PING"
+	PongCmd         = "PONG"
+	PrivMsgCmd      = "PRIVMSG"
+	StatsCmd        = "STATS"
+	TagMsgCmd       = "TAGMSG"
+	TimeCmd         = "TIME"
+	TopicCmd        = "TOPIC"
+	UserCmd         = "USER"
+	UserHostCmd     = "USERHOST"
+	VersionCmd      = "VERSION"
+	RegChanCmd      = "REGCHAN"
+	RegisterCmd     = "REGISTER"
+	QuitCmd         = "QUIT"
+	SetMotdCmd      = "SETMOTD"
+	WhoCmd          = "WHO"
+	WhoisCmd        = "WHOIS"
+	WhoWasCmd       = "WHOWAS"
 )
@@ -1,11 +1,17 @@
 package irc
 
 var (
-	BucketConfig = []byte("config")
-	BucketOpers  = []byte("opers")
+	BucketAccounts = []byte("accounts")
+	BucketCertFPs  = []byte("certfps")
+	BucketChannels = []byte("channels")
+	BucketConfig   = []byte("config")
+	BucketOpers    = []byte("opers")
 )
 
 var Buckets [][]byte = [][]byte{
+	BucketAccounts,
+	BucketCertFPs,
+	BucketChannels,
 	BucketConfig,
 	BucketOpers,
 }
@@ -15,6 +21,7 @@ var (
 	ConfigSalt = []byte("salt")
 	ConfigCert = []byte("cert")
 	ConfigKey  = []byte("key")
+	ConfigMotd = []byte("motd")
 )
 
 var (
@@ -22,4 +29,25 @@ var (
 	OperSalt = []byte("salt")
 )
 
+var (
+	AccountName   = []byte("name")
+	AccountPass   = []byte("pass")
+	AccountSalt   = []byte("salt")
+	AccountCertFP = []byte("certfp")
+)
+
+var (
+	ChannelName       = []byte("name")
+	ChannelFounder    = []byte("founder")
+	ChannelTopic      = []byte("topic")
+	ChannelTopicTime  = []byte("topictime")
+	ChannelModes      = []byte("modes")
+	ChannelKey        = []byte("key")
+	ChannelLimit      = []byte("limit")
+	ChannelBans       = []byte("bans")
+	ChannelExceptions = []byte("exceptions")
+	ChannelInvex      = []byte("invex")
+	ChannelAccess     = []byte("access")
+)
+
 var DefaultOper = []byte("irc")
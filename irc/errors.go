@@ -7,43 +7,80 @@ import (
 
 const (
 	ErrAlreadyRegistered = "462"
+	ErrBadChanMask       = "476"
 	ErrBadChannelKey     = "475"
+	ErrBanListFull       = "478"
+	ErrBannedFromChan    = "474"
 	ErrCannotSendToChan  = "404"
 	ErrChannelIsFull     = "471"
 	ErrChanOpPrivsNeeded = "482"
+	ErrErroneusNickname  = "432"
+	ErrInputTooLong      = "417"
 	ErrInvalidCapCmd     = "410"
+	ErrInviteOnlyChan    = "473"
 	ErrNeedMoreParams    = "461"
+	ErrNickLocked        = "902"
 	ErrNickNameInUse     = "433"
+	ErrNoAdminInfo       = "423"
 	ErrNoMotd            = "422"
 	ErrNoNickNameGiven   = "431"
+	ErrNoPrivileges      = "481"
+	ErrNoRecipient       = "411"
 	ErrNoSuchChannel     = "403"
 	ErrNoSuchNick        = "401"
+	ErrNoTextToSend      = "412"
 	ErrNotOnChannel      = "442"
 	ErrNotRegistered     = "451"
 	ErrPasswordMismatch  = "464"
+	ErrSaslAborted       = "906"
+	ErrSaslAlready       = "907"
+	ErrSaslFail          = "904"
+	ErrSaslTooLong       = "905"
 	ErrUModeUnknownFlag  = "501"
 	ErrUnknownMode       = "472"
+	ErrUserNotInChannel  = "441"
+	ErrUserOnChannel     = "443"
 	ErrUsersDontMatch    = "502"
+	ErrWasNoSuchNick     = "406"
 )
 
 var ErrorText = map[string]string{
 	ErrAlreadyRegistered: "Unauthorized command (already registered)",
+	ErrBadChanMask:       "Bad Channel Mask",
 	ErrBadChannelKey:     "Cannot join channel (+k)",
+	ErrBanListFull:       "Channel list is full",
+	ErrBannedFromChan:    "Cannot join channel (+b)",
 	ErrCannotSendToChan:  "Cannot send to channel",
 	ErrChannelIsFull:     "Cannot join channel (+l)",
 	ErrChanOpPrivsNeeded: "You're not channel operator",
+	ErrErroneusNickname:  "Erroneous nickname",
+	ErrInputTooLong:      "Input line was too long",
 	ErrInvalidCapCmd:     "Invalid CAP command",
+	ErrInviteOnlyChan:    "Cannot join channel (+i)",
 	ErrNeedMoreParams:    "Not enough parameters",
+	ErrNickLocked:        "You must use a nick assigned to you",
 	ErrNickNameInUse:     "Nickname is already in use",
+	ErrNoAdminInfo:       "No administrative info available",
+	ErrNoMotd:            "MOTD File is missing",
 	ErrNoNickNameGiven:   "No nickname given",
+	ErrNoPrivileges:      "Permission Denied- You're not an IRC operator",
+	ErrNoRecipient:       "No recipient given",
 	ErrNoSuchChannel:     "No such channel",
 	ErrNoSuchNick:        "No such nick/channel",
+	ErrNoTextToSend:      "No text to send",
 	ErrNotOnChannel:      "You're not on that channel",
 	ErrNotRegistered:     "You have not registered",
 	ErrPasswordMismatch:  "Password incorrect",
+	ErrSaslAborted:       "SASL authentication aborted",
+	ErrSaslAlready:       "You have already authenticated using SASL",
+	ErrSaslFail:          "SASL authentication failed",
+	ErrSaslTooLong:       "SASL message too long",
 	ErrUModeUnknownFlag:  "Unknown MODE flag",
 	ErrUnknownMode:       "is unknown mode char to me",
+	ErrUserNotInChannel:  "They aren't on that channel",
+	ErrUserOnChannel:     "is already on channel",
 	ErrUsersDontMatch:    "Cannot change mode for other users",
+	ErrWasNoSuchNick:     "There was no such nickname",
 }
 
 var Quit = errors.New("quit")
@@ -0,0 +1,59 @@
+package irc
+
+import (
+	"time"
+
+	"github.com/blackchip-org/chatty/internal/clock"
+)
+
+// Defaults for command throttling. A client may send a burst of commands
+// after which it is limited to the rate in commands per second. Commands
+// over the rate are held back and the client is disconnected once more
+// than the backlog are waiting.
+const (
+	DefaultFloodBacklog = 20
+	DefaultFloodBurst   = 10
+	DefaultFloodRate    = 1.0
+)
+
+// Throttle limits the commands sent by a client using a token bucket. Like
+// the penalty timer in RFC 2813 section 5.8, each command costs a fixed
+// amount and the allowance is restored over time up to the burst size.
+// https://tools.ietf.org/html/rfc2813#section-5.8
+type Throttle struct {
+	burst  float64
+	rate   float64
+	tokens float64
+	last   time.Time
+	clk    clock.C
+}
+
+func NewThrottle(burst int, rate float64) *Throttle {
+	return newThrottle(burst, rate, clock.Real{})
+}
+
+func newThrottle(burst int, rate float64, clk clock.C) *Throttle {
+	return &Throttle{
+		burst:  float64(burst),
+		rate:   rate,
+		tokens: float64(burst),
+		last:   clk.Now(),
+		clk:    clk,
+	}
+}
+
+// Delay takes a command from the allowance and returns how long to wait
+// before processing it. It is zero while the allowance lasts.
+func (t *Throttle) Delay() time.Duration {
+	now := t.clk.Now()
+	t.tokens += now.Sub(t.last).Seconds() * t.rate
+	if t.tokens > t.burst {
+		t.tokens = t.burst
+	}
+	t.last = now
+	t.tokens--
+	if t.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-t.tokens / t.rate * float64(time.Second))
+}
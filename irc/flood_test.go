@@ -0,0 +1,51 @@
+package irc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blackchip-org/chatty/internal/clock"
+)
+
+func TestThrottleBurst(t *testing.T) {
+	clk := &clock.Mock{}
+	th := newThrottle(3, 1, clk)
+	for i := 0; i < 3; i++ {
+		if d := th.Delay(); d != 0 {
+			t.Fatalf("command %v should not be delayed: %v", i, d)
+		}
+	}
+	if d := th.Delay(); d != time.Second {
+		t.Fatalf("command after burst should be delayed 1s: %v", d)
+	}
+}
+
+func TestThrottleRate(t *testing.T) {
+	clk := &clock.Mock{}
+	th := newThrottle(3, 0.5, clk)
+	for i := 0; i < 3; i++ {
+		th.Delay()
+	}
+	clk.Add(1 * time.Second)
+	if d := th.Delay(); d != time.Second {
+		t.Fatalf("command should be delayed until the penalty expires: %v", d)
+	}
+	clk.Add(3 * time.Second)
+	if d := th.Delay(); d != 0 {
+		t.Fatalf("command should not be delayed after penalty expires: %v", d)
+	}
+}
+
+func TestThrottleRefillLimit(t *testing.T) {
+	clk := &clock.Mock{}
+	th := newThrottle(3, 1, clk)
+	clk.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if d := th.Delay(); d != 0 {
+			t.Fatalf("command %v should not be delayed: %v", i, d)
+		}
+	}
+	if d := th.Delay(); d == 0 {
+		t.Fatalf("allowance should not exceed the burst")
+	}
+}
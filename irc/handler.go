@@ -2,9 +2,11 @@ package irc
 
 import (
 	"bytes"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,6 +14,9 @@ import (
 	"github.com/boltdb/bolt"
 )
 
+// UserHostMaxNicks is the number of nicks that can be given to USERHOST.
+const UserHostMaxNicks = 5
+
 type Handler interface {
 	Handle(Command) error
 }
@@ -31,10 +36,13 @@ type DefaultHandler struct {
 }
 
 var prereg = map[string]bool{
-	PassCmd: true,
-	NickCmd: true,
-	UserCmd: true,
-	CapCmd:  true,
+	AuthenticateCmd: true,
+	PassCmd:         true,
+	NickCmd:         true,
+	PongCmd:         true,
+	RegisterCmd:     true,
+	UserCmd:         true,
+	CapCmd:          true,
 }
 
 func (h *DefaultHandler) Handle(cmd Command) error {
@@ -42,21 +50,52 @@ func (h *DefaultHandler) Handle(cmd Command) error {
 		allowed := prereg[cmd.Name]
 		if !allowed {
 			h.c.SendError(NewError(ErrNotRegistered))
-			return h.c.err
+			return h.c.Err()
 		}
 	}
+	if cmd.Name != PingCmd && cmd.Name != PongCmd {
+		h.c.markActive()
+	}
 
 	switch cmd.Name {
+	case AccessCmd:
+		h.access(cmd.Params)
+	case AdminCmd:
+		h.admin()
+	case AuthenticateCmd:
+		h.authenticate(cmd.Params)
+	case AwayCmd:
+		h.away(cmd.Params)
 	case CapCmd:
 		h.cap(cmd.Params)
+	case CertFPCmd:
+		h.certFP(cmd.Params)
+	case DropChanCmd:
+		h.dropChan(cmd.Params)
+	case InfoCmd:
+		h.info()
+	case InviteCmd:
+		h.invite(cmd.Params)
+	case IsonCmd:
+		h.ison(cmd.Params)
 	case JoinCmd:
 		h.join(cmd.Params)
+	case KickCmd:
+		h.kick(cmd.Params)
+	case ListCmd:
+		h.list(cmd.Params)
+	case LusersCmd:
+		h.s.Lusers(h.c)
 	case ModeCmd:
 		h.mode(cmd.Params)
+	case MotdCmd:
+		h.motd()
 	case NamesCmd:
 		h.names(cmd.Params)
 	case NickCmd:
 		h.nick(cmd.Params)
+	case NoticeCmd:
+		h.notice(cmd.Params, ClientTags(cmd.Tags))
 	case OperCmd:
 		h.oper(cmd.Params)
 	case PartCmd:
@@ -65,57 +104,439 @@ func (h *DefaultHandler) Handle(cmd Command) error {
 		h.pass(cmd.Params)
 	case PingCmd:
 		h.ping(cmd.Params)
+	case PongCmd:
+		// Receiving any line resets the ping timeout
 	case PrivMsgCmd:
-		h.privMsg(cmd.Params)
+		h.privMsg(cmd.Params, ClientTags(cmd.Tags))
+	case RegChanCmd:
+		h.regChan(cmd.Params)
+	case RegisterCmd:
+		h.register(cmd.Params)
+	case SetMotdCmd:
+		h.setMotd(cmd.Params)
+	case StatsCmd:
+		h.stats(cmd.Params)
+	case TagMsgCmd:
+		h.tagMsg(cmd.Params, ClientTags(cmd.Tags))
+	case TimeCmd:
+		h.time()
 	case TopicCmd:
 		h.topic(cmd.Params)
 	case UserCmd:
 		h.user(cmd.Params)
+	case UserHostCmd:
+		h.userHost(cmd.Params)
+	case VersionCmd:
+		h.version()
 	case QuitCmd:
 		h.quit(cmd.Params)
 	case WhoCmd:
 		h.who(cmd.Params)
+	case WhoisCmd:
+		h.whois(cmd.Params)
+	case WhoWasCmd:
+		h.whoWas(cmd.Params)
 	default:
 		log.Printf("unhandled message: %+v", cmd)
 	}
-	return h.c.err
+	return h.c.Err()
+}
+
+// ACCESS <channel> [LIST]
+// ACCESS <channel> ADD <account> <o|v>
+// ACCESS <channel> DEL <account>
+func (h *DefaultHandler) access(params []string) {
+	if len(params) == 0 {
+		h.c.SendError(NewError(ErrNeedMoreParams, AccessCmd))
+		return
+	}
+	chname := params[0]
+	subcmd := "LIST"
+	if len(params) > 1 {
+		subcmd = strings.ToUpper(params[1])
+	}
+	var err error
+	switch {
+	case subcmd == "LIST":
+		var list [][2]string
+		list, err = h.s.AccessList(h.c, chname)
+		if err == nil {
+			for _, entry := range list {
+				h.c.Send(AccessCmd, chname, "LIST", entry[0], entry[1])
+			}
+			h.c.Send(AccessCmd, chname, "END")
+			return
+		}
+	case subcmd == "ADD" && len(params) == 4:
+		if params[3] != ChanModeOper && params[3] != ChanModeVoice {
+			h.c.Fail(AccessCmd, "INVALID_MODE", chname, params[3], "Mode must be o or v")
+			return
+		}
+		err = h.s.SetAccess(h.c, chname, params[2], params[3])
+	case subcmd == "DEL" && len(params) == 3:
+		err = h.s.SetAccess(h.c, chname, params[2], "")
+	default:
+		h.c.SendError(NewError(ErrNeedMoreParams, AccessCmd))
+		return
+	}
+	switch err {
+	case nil:
+		h.c.Send(AccessCmd, "SUCCESS", chname, "Access list updated")
+	case errNoSuchAccount:
+		h.c.Fail(AccessCmd, "NO_SUCH_ACCOUNT", chname, params[2], "No such account")
+	default:
+		h.chanRegFail(AccessCmd, chname, err)
+	}
+}
+
+func (h *DefaultHandler) admin() {
+	a := h.s.Admin
+	if a == (Admin{}) {
+		h.c.SendError(NewError(ErrNoAdminInfo, h.s.Origin()))
+		return
+	}
+	h.c.Reply(RplAdminMe, h.s.Origin()).
+		Reply(RplAdminLoc1, a.Location).
+		Reply(RplAdminLoc2, a.Description).
+		Reply(RplAdminEmail, a.Email)
+}
+
+func (h *DefaultHandler) authenticate(params []string) {
+	if len(params) == 0 {
+		h.c.SendError(NewError(ErrNeedMoreParams, AuthenticateCmd))
+		return
+	}
+	if !h.c.HasCap(CapSasl) {
+		h.c.SendError(NewError(ErrSaslFail))
+		return
+	}
+	if h.c.User.Account != "" {
+		h.c.SendError(NewError(ErrSaslAlready))
+		return
+	}
+	arg := params[0]
+	if arg == "*" {
+		h.saslReset()
+		h.c.SendError(NewError(ErrSaslAborted))
+		return
+	}
+	if h.c.saslMech == "" {
+		h.saslStart(strings.ToUpper(arg))
+		return
+	}
+	if len(arg) > SaslChunkLen || h.c.saslData.Len()+len(arg) > SaslMaxLen {
+		h.saslReset()
+		h.c.SendError(NewError(ErrSaslTooLong))
+		return
+	}
+	if arg != "+" {
+		h.c.saslData.WriteString(arg)
+	}
+	if len(arg) == SaslChunkLen {
+		return
+	}
+	response, err := base64.StdEncoding.DecodeString(h.c.saslData.String())
+	mech := h.c.saslMech
+	h.saslReset()
+	if err != nil {
+		h.saslFail()
+		return
+	}
+	h.saslFinish(mech, response)
+}
+
+func (h *DefaultHandler) saslStart(mech string) {
+	for _, supported := range strings.Split(h.s.Caps()[CapSasl], ",") {
+		if mech == supported {
+			h.c.saslMech = mech
+			h.c.SendMessage(Message{
+				Prefix:   h.c.ServerName,
+				Cmd:      AuthenticateCmd,
+				Params:   []string{"+"},
+				NoSpaces: true,
+			})
+			return
+		}
+	}
+	h.c.Reply(RplSaslMechs, h.s.Caps()[CapSasl])
+	h.c.SendError(NewError(ErrSaslFail))
+}
+
+func (h *DefaultHandler) saslFinish(mech string, response []byte) {
+	var account string
+	var err error
+	switch mech {
+	case SaslPlain:
+		// authzid NUL authcid NUL passwd
+		fields := bytes.Split(response, []byte{0})
+		if len(fields) != 3 {
+			err = errAuthFailed
+			break
+		}
+		authzid, authcid := string(fields[0]), string(fields[1])
+		if authzid != "" && h.s.fold(authzid) != h.s.fold(authcid) {
+			err = errAuthFailed
+			break
+		}
+		account, err = h.s.authPlain(authcid, string(fields[2]))
+	case SaslExternal:
+		account, err = h.s.authCert(h.c.CertFP())
+		if err == nil && len(response) > 0 && h.s.fold(string(response)) != h.s.fold(account) {
+			err = errAuthFailed
+		}
+	}
+	if err != nil {
+		h.saslFail()
+		return
+	}
+	h.s.logIn(h.c, account)
+	h.c.Reply(RplLoggedIn, h.c.User.Origin(), account, "You are now logged in as "+account)
+	h.c.Reply(RplSaslSuccess)
+}
+
+// saslFail rejects an attempt to authenticate and closes the connection
+// once the client has failed too many times.
+func (h *DefaultHandler) saslFail() {
+	h.c.SendError(NewError(ErrSaslFail))
+	h.c.saslFails++
+	if h.c.saslFails >= SaslMaxFails {
+		h.c.close("Too many SASL failures")
+	}
+}
+
+func (h *DefaultHandler) saslReset() {
+	h.c.saslMech = ""
+	h.c.saslData.Reset()
+}
+
+func (h *DefaultHandler) away(params []string) {
+	msg := ""
+	if len(params) > 0 {
+		msg = params[0]
+	}
+	h.s.Away(h.c, msg)
 }
 
 func (h *DefaultHandler) cap(params []string) {
 	if len(params) == 0 {
-		h.c.SendError(NewError(ErrNeedMoreParams))
+		h.c.SendError(NewError(ErrNeedMoreParams, CapCmd))
 		return
 	}
 	capcmd := params[0]
 	switch capcmd {
 	case CapLsCmd:
-		h.c.Reply(CapCmd, CapLsCmd)
+		if !h.c.registered {
+			h.c.capNegotiate = true
+		}
+		if len(params) > 1 {
+			if version, err := strconv.Atoi(params[1]); err == nil && version >= CapVersion {
+				h.c.setCapVersion(CapVersion)
+			}
+		}
+		h.capReply(CapLsCmd, capList(h.s.Caps(), h.c.capVersion))
+	case CapListCmd:
+		h.capReply(CapListCmd, h.c.EnabledCaps())
 	case CapReqCmd:
-		h.c.Reply("CAP", "*", "ACK", "multi-prefix")
+		if !h.c.registered {
+			h.c.capNegotiate = true
+		}
+		if len(params) < 2 {
+			h.c.SendError(NewError(ErrNeedMoreParams, CapCmd))
+			return
+		}
+		h.capReq(params[1])
 	case CapEndCmd:
-		h.welcome()
+		if !h.c.registered && h.c.capNegotiate {
+			h.c.capNegotiate = false
+			h.checkHandshake()
+		}
 	default:
 		h.c.SendError(NewError(ErrInvalidCapCmd, capcmd))
 	}
 }
 
+// All requested capabilities are enabled or none are. A capability prefixed
+// with a dash is disabled. Clients that support version 302 cannot disable
+// cap-notify.
+func (h *DefaultHandler) capReq(list string) {
+	names := strings.Fields(list)
+	version, _ := h.c.capNotify()
+	for _, name := range names {
+		locked := name == "-"+CapCapNotify && version >= CapVersion
+		if locked || !h.s.hasCap(strings.TrimPrefix(name, "-")) {
+			h.capReply(CapNakCmd, []string{list})
+			return
+		}
+	}
+	for _, name := range names {
+		h.c.setCap(strings.TrimPrefix(name, "-"), !strings.HasPrefix(name, "-"))
+	}
+	h.capReply(CapAckCmd, []string{list})
+}
+
+// Long lists are split over several replies. Clients that support version
+// 302 are sent a * on every reply but the last.
+func (h *DefaultHandler) capReply(subcmd string, list []string) {
+	nick := "*"
+	if h.c.User.Nick != "" {
+		nick = h.c.User.Nick
+	}
+	m := Message{Prefix: h.c.ServerName, Cmd: CapCmd, Params: []string{nick, subcmd, "*", ""}}
+	max := MessageMaxLen - len(m.Encode()) - 2
+	lines := SplitWords(list, max)
+	for i, line := range lines {
+		if i < len(lines)-1 && h.c.capVersion >= CapVersion {
+			h.c.Send(CapCmd, nick, subcmd, "*", line)
+		} else {
+			h.c.Send(CapCmd, nick, subcmd, line)
+		}
+	}
+}
+
+// CERTFP [ADD|DEL]
+// Sets the fingerprint of the client certificate used on this connection
+// as the one that logs in to the account with SASL EXTERNAL, or removes
+// it.
+func (h *DefaultHandler) certFP(params []string) {
+	subcmd := "ADD"
+	if len(params) > 0 {
+		subcmd = strings.ToUpper(params[0])
+	}
+	account := h.c.User.Account
+	if account == "" {
+		h.c.Fail(CertFPCmd, "ACCOUNT_REQUIRED", "You must be logged in")
+		return
+	}
+	certfp := ""
+	switch subcmd {
+	case "ADD":
+		certfp = h.c.CertFP()
+		if certfp == "" {
+			h.c.Fail(CertFPCmd, "NO_CERTIFICATE", "Connect with a client certificate to set it")
+			return
+		}
+	case "DEL":
+	default:
+		h.c.SendError(NewError(ErrNeedMoreParams, CertFPCmd))
+		return
+	}
+	switch err := h.s.SetAccountCert(account, certfp); err {
+	case nil:
+		h.c.Send(CertFPCmd, "SUCCESS", account, "Certificate fingerprint updated")
+	case errCertInUse:
+		h.c.Fail(CertFPCmd, "CERT_IN_USE", account, "Certificate is used by another account")
+	default:
+		log.Printf("unable to set certificate for %v: %v", account, err)
+		h.c.Fail(CertFPCmd, "TEMPORARILY_UNAVAILABLE", account, "Unable to set certificate")
+	}
+}
+
+func (h *DefaultHandler) dropChan(params []string) {
+	if len(params) == 0 {
+		h.c.SendError(NewError(ErrNeedMoreParams, DropChanCmd))
+		return
+	}
+	chname := params[0]
+	if err := h.s.DropChan(h.c, chname); err != nil {
+		h.chanRegFail(DropChanCmd, chname, err)
+		return
+	}
+	h.c.Send(DropChanCmd, "SUCCESS", chname, "Channel registration dropped")
+}
+
+func (h *DefaultHandler) info() {
+	lines := []string{
+		fmt.Sprintf("%v version %v", h.s.Origin(), Version),
+		"https://github.com/blackchip-org/chatty",
+		fmt.Sprintf("Started on %v", h.s.Started.Format(time.RFC1123)),
+	}
+	for _, line := range lines {
+		h.c.Reply(RplInfo, line)
+	}
+	h.c.Reply(RplEndOfInfo)
+}
+
+func (h *DefaultHandler) invite(params []string) {
+	if len(params) < 2 {
+		h.c.SendError(NewError(ErrNeedMoreParams, InviteCmd))
+		return
+	}
+	nick := params[0]
+	chname := params[1]
+	if err := h.s.Invite(h.c, nick, chname); err != nil {
+		h.c.SendError(err)
+	}
+}
+
+// Nicks may be given as separate parameters or space separated in the
+// final parameter.
+func (h *DefaultHandler) ison(params []string) {
+	if len(params) == 0 {
+		h.c.SendError(NewError(ErrNeedMoreParams, IsonCmd))
+		return
+	}
+	h.s.Ison(h.c, strings.Fields(strings.Join(params, " ")))
+}
+
 func (h *DefaultHandler) join(params []string) {
 	if len(params) == 0 {
 		h.c.SendError(NewError(ErrNeedMoreParams, JoinCmd))
 		return
 	}
-	name := params[0]
-	key := ""
+	var keys []string
 	if len(params) > 1 {
-		key = params[1]
+		keys = strings.Split(params[1], ",")
 	}
-	_, err := h.s.Join(h.c, name, key)
-	if err != nil {
-		h.c.SendError(err)
+	for i, name := range strings.Split(params[0], ",") {
+		key := ""
+		if i < len(keys) {
+			key = keys[i]
+		}
+		_, err := h.s.Join(h.c, name, key)
+		if err != nil {
+			h.c.SendError(err)
+			continue
+		}
+		h.topic([]string{name})
+		h.names([]string{name})
+	}
+}
+
+// A single channel may be given with a list of nicks, otherwise each
+// channel is paired with the nick at the same position.
+func (h *DefaultHandler) kick(params []string) {
+	if len(params) < 2 {
+		h.c.SendError(NewError(ErrNeedMoreParams, KickCmd))
+		return
+	}
+	chnames := strings.Split(params[0], ",")
+	nicks := strings.Split(params[1], ",")
+	if len(chnames) != 1 && len(chnames) != len(nicks) {
+		h.c.SendError(NewError(ErrNeedMoreParams, KickCmd))
 		return
 	}
-	h.topic([]string{name})
-	h.names([]string{name})
+	reason := h.c.User.Nick
+	if len(params) > 2 && params[2] != "" {
+		reason = params[2]
+	}
+	for i, nick := range nicks {
+		chname := chnames[0]
+		if len(chnames) > 1 {
+			chname = chnames[i]
+		}
+		if err := h.s.Kick(h.c, chname, nick, reason); err != nil {
+			h.c.SendError(err)
+		}
+	}
+}
+
+// The optional server parameter is ignored as there is only one server.
+func (h *DefaultHandler) list(params []string) {
+	filters := ""
+	if len(params) > 0 {
+		filters = params[0]
+	}
+	h.s.List(h.c, ParseListFilter(filters))
 }
 
 func (h *DefaultHandler) mode(params []string) {
@@ -164,11 +585,25 @@ func (h *DefaultHandler) modeChan(params []string) {
 	params = params[1:]
 	requests := parseChanModes(params)
 	cmds := ch.SetMode(h.c)
+	nparams := 0
 	for _, req := range requests {
+		// Ignore modes with parameters past the limit
+		if req.Param != "" {
+			nparams++
+			if nparams > ChanModesMaxParams {
+				continue
+			}
+		}
 		var err error
 		switch req.Char {
 		case ChanModeBan:
 			err = cmds.Ban(req.Action, req.Param)
+		case ChanModeBanException:
+			err = cmds.BanException(req.Action, req.Param)
+		case ChanModeInvitationMask:
+			err = cmds.InvitationMask(req.Action, req.Param)
+		case ChanModeInviteOnly:
+			err = cmds.InviteOnly(req.Action)
 		case ChanModeKeylock:
 			err = cmds.Keylock(req.Action, req.Param)
 		case ChanModeLimit:
@@ -177,6 +612,8 @@ func (h *DefaultHandler) modeChan(params []string) {
 			err = cmds.Moderated(req.Action)
 		case ChanModeNoExternalMsgs:
 			err = cmds.NoExternalMsgs(req.Action)
+		case ChanModeSecret:
+			err = cmds.Secret(req.Action)
 		case ChanModeTopicLock:
 			err = cmds.TopicLock(req.Action)
 		case ChanModeOper:
@@ -200,7 +637,7 @@ func (h *DefaultHandler) modeUser(params []string) {
 		return
 	}
 	nick := params[0]
-	if nick != h.c.User.Nick {
+	if h.s.fold(nick) != h.s.fold(h.c.User.Nick) {
 		h.c.SendError(NewError(ErrUsersDontMatch))
 	}
 	requests := parseUserModes(params[1:])
@@ -221,6 +658,22 @@ func (h *DefaultHandler) modeUser(params []string) {
 	cmds.Done()
 }
 
+func (h *DefaultHandler) motd() {
+	lines, err := h.s.Motd()
+	if err != nil {
+		log.Printf("unable to read motd: %v", err)
+	}
+	if len(lines) == 0 {
+		h.c.SendError(NewError(ErrNoMotd))
+		return
+	}
+	h.c.Reply(RplMotdStart, fmt.Sprintf("- %v Message of the day - ", h.s.Origin()))
+	for _, line := range lines {
+		h.c.Reply(RplMotd, "- "+line)
+	}
+	h.c.Reply(RplEndOfMotd)
+}
+
 func (h *DefaultHandler) names(params []string) {
 	if len(params) == 0 {
 		h.c.Send(RplEndOfNames)
@@ -247,7 +700,23 @@ func (h *DefaultHandler) nick(params []string) {
 		h.c.SendError(err)
 		return
 	}
-	h.checkHandshake()
+	if !h.c.registered {
+		h.checkHandshake()
+	}
+}
+
+// Errors are never sent in response to a notice
+func (h *DefaultHandler) notice(params []string, tags map[string]string) {
+	if len(params) < 2 || params[1] == "" {
+		return
+	}
+	text := params[1]
+	for _, target := range strings.Split(params[0], ",") {
+		if target == "" {
+			continue
+		}
+		h.s.Notice(h.c, target, text, tags)
+	}
 }
 
 func (h *DefaultHandler) oper(params []string) {
@@ -311,13 +780,65 @@ func (h *DefaultHandler) ping(params []string) {
 	h.c.Send(PongCmd, outparams...)
 }
 
-func (h *DefaultHandler) privMsg(params []string) {
-	target := params[0]
+func (h *DefaultHandler) privMsg(params []string, tags map[string]string) {
+	if len(params) == 0 {
+		h.c.SendError(NewError(ErrNoRecipient))
+		return
+	}
+	if len(params) == 1 || params[1] == "" {
+		h.c.SendError(NewError(ErrNoTextToSend))
+		return
+	}
 	text := params[1]
-	err := h.s.PrivMsg(h.c, target, text)
-	if err != nil {
+	for _, target := range strings.Split(params[0], ",") {
+		if target == "" {
+			continue
+		}
+		if err := h.s.PrivMsg(h.c, target, text, tags); err != nil {
+			h.c.SendError(err)
+		}
+	}
+}
+
+func (h *DefaultHandler) setMotd(params []string) {
+	text := ""
+	if len(params) > 0 {
+		text = params[0]
+	}
+	if err := h.s.SetMotd(h.c, text); err != nil {
 		h.c.SendError(err)
+		return
 	}
+	h.motd()
+}
+
+func (h *DefaultHandler) stats(params []string) {
+	if len(params) == 0 {
+		h.c.SendError(NewError(ErrNeedMoreParams, StatsCmd))
+		return
+	}
+	if err := h.s.Stats(h.c, params[0]); err != nil {
+		h.c.SendError(err)
+	}
+}
+
+func (h *DefaultHandler) tagMsg(params []string, tags map[string]string) {
+	if len(params) == 0 {
+		h.c.SendError(NewError(ErrNoRecipient))
+		return
+	}
+	for _, target := range strings.Split(params[0], ",") {
+		if target == "" {
+			continue
+		}
+		if err := h.s.TagMsg(h.c, target, tags); err != nil {
+			h.c.SendError(err)
+		}
+	}
+}
+
+func (h *DefaultHandler) time() {
+	h.c.Reply(RplTime, h.s.Origin(), time.Now().Format(time.RFC1123))
 }
 
 func (h *DefaultHandler) topic(params []string) {
@@ -362,6 +883,75 @@ func (h *DefaultHandler) quit(params []string) {
 	h.s.Quit(h.c, reason)
 }
 
+// Channels are registered to the account of a channel operator
+func (h *DefaultHandler) regChan(params []string) {
+	if len(params) == 0 {
+		h.c.SendError(NewError(ErrNeedMoreParams, RegChanCmd))
+		return
+	}
+	chname := params[0]
+	if err := h.s.RegisterChan(h.c, chname); err != nil {
+		h.chanRegFail(RegChanCmd, chname, err)
+		return
+	}
+	h.c.Send(RegChanCmd, "SUCCESS", chname, "Channel registered")
+}
+
+func (h *DefaultHandler) chanRegFail(cmd string, chname string, err error) {
+	switch err {
+	case errNoSuchAccount:
+		h.c.Fail(cmd, "ACCOUNT_REQUIRED", chname, "You must be logged in")
+	case errChanRegistered:
+		h.c.Fail(cmd, "ALREADY_REGISTERED", chname, "Channel is already registered")
+	case errChanNotRegistered:
+		h.c.Fail(cmd, "NOT_REGISTERED", chname, "Channel is not registered")
+	case errNotFounder:
+		h.c.Fail(cmd, "NOT_FOUNDER", chname, "You are not the channel founder")
+	default:
+		h.c.SendError(err)
+	}
+}
+
+// https://ircv3.net/specs/extensions/account-registration
+// The email address is not used.
+func (h *DefaultHandler) register(params []string) {
+	if len(params) < 3 {
+		h.c.SendError(NewError(ErrNeedMoreParams, RegisterCmd))
+		return
+	}
+	account, password := params[0], params[2]
+	if !h.c.registered && !h.s.registerBeforeConnect() {
+		h.c.Fail(RegisterCmd, "COMPLETE_CONNECTION_REQUIRED", account, "Complete the connection before registering an account")
+		return
+	}
+	if account == "*" {
+		if h.c.User.Nick == "" {
+			h.c.Fail(RegisterCmd, "NEED_NICK", "*", "Send NICK before registering an account")
+			return
+		}
+		account = h.c.User.Nick
+	}
+	if h.c.User.Account != "" {
+		h.c.Fail(RegisterCmd, "ALREADY_AUTHENTICATED", h.c.User.Account, "You are already logged in")
+		return
+	}
+	switch err := h.s.Register(h.c, account, password); err {
+	case nil:
+		h.c.Send(RegisterCmd, "SUCCESS", account, "Account created")
+		h.c.Reply(RplLoggedIn, h.c.User.Origin(), account, "You are now logged in as "+account)
+	case errAccountExists:
+		h.c.Fail(RegisterCmd, "ACCOUNT_EXISTS", account, "Account already exists")
+	case errBadAccountName:
+		h.c.Fail(RegisterCmd, "BAD_ACCOUNT_NAME", account, "Account name is not valid or in use")
+	case errWeakPassword:
+		h.c.Fail(RegisterCmd, "WEAK_PASSWORD", account,
+			fmt.Sprintf("Password must be at least %v characters", AccountPassMinLen))
+	default:
+		log.Printf("unable to register account %v: %v", account, err)
+		h.c.Fail(RegisterCmd, "TEMPORARILY_UNAVAILABLE", account, "Unable to create account")
+	}
+}
+
 func (h *DefaultHandler) user(params []string) {
 	if h.c.registered {
 		h.c.SendError(NewError(ErrAlreadyRegistered))
@@ -376,6 +966,27 @@ func (h *DefaultHandler) user(params []string) {
 	h.checkHandshake()
 }
 
+// userHost replies with the host of each nick that is online. Only the
+// first UserHostMaxNicks are looked up.
+func (h *DefaultHandler) userHost(params []string) {
+	if len(params) == 0 {
+		h.c.SendError(NewError(ErrNeedMoreParams, UserHostCmd))
+		return
+	}
+	nicks := strings.Fields(strings.Join(params, " "))
+	if len(nicks) > UserHostMaxNicks {
+		nicks = nicks[:UserHostMaxNicks]
+	}
+	h.s.UserHost(h.c, nicks)
+}
+
+// The ISUPPORT tokens are sent again after the version as required by
+// https://modern.ircdocs.horse/#version-message
+func (h *DefaultHandler) version() {
+	h.c.Reply(RplVersion, Version, h.s.Origin(), "https://github.com/blackchip-org/chatty")
+	h.isupport()
+}
+
 // http://chi.cs.uchicago.edu/chirc/assignment3.html#who
 // Only channels at the moment
 func (h *DefaultHandler) who(params []string) {
@@ -392,6 +1003,9 @@ func (h *DefaultHandler) who(params []string) {
 	members := ch.Members()
 	for _, member := range members {
 		avail := "H"
+		if h.s.away(member.User.ID) {
+			avail = "G"
+		}
 		op := ""
 		prefix := ch.modes.UserPrefix(member.User.ID)
 		params := []string{
@@ -408,22 +1022,74 @@ func (h *DefaultHandler) who(params []string) {
 	h.c.Reply(RplEndOfWho, ch.name)
 }
 
+// The optional server parameter is ignored as there is only one server.
+func (h *DefaultHandler) whois(params []string) {
+	if len(params) == 0 {
+		h.c.SendError(NewError(ErrNoNickNameGiven))
+		return
+	}
+	nicks := params[len(params)-1]
+	for _, nick := range strings.Split(nicks, ",") {
+		if nick == "" {
+			continue
+		}
+		if err := h.s.Whois(h.c, nick); err != nil {
+			h.c.SendError(err)
+		}
+	}
+	h.c.Reply(RplEndOfWhois, nicks)
+}
+
+func (h *DefaultHandler) whoWas(params []string) {
+	if len(params) == 0 {
+		h.c.SendError(NewError(ErrNoNickNameGiven))
+		return
+	}
+	count := 0
+	if len(params) > 1 {
+		if n, err := strconv.Atoi(params[1]); err == nil {
+			count = n
+		}
+	}
+	nicks := params[0]
+	for _, nick := range strings.Split(nicks, ",") {
+		if nick == "" {
+			continue
+		}
+		if err := h.s.WhoWas(h.c, nick, count); err != nil {
+			h.c.SendError(err)
+		}
+	}
+	h.c.Reply(RplEndOfWhoWas, nicks)
+}
+
 // ===============
 
+// Registration completes once both NICK and USER have been received and
+// any capability negotiation has ended.
 func (h *DefaultHandler) checkHandshake() error {
-	if h.c.User.Nick != "" && h.c.User.Name != "" {
+	if h.c.User.Nick != "" && h.c.User.Name != "" && !h.c.capNegotiate {
 		if err := h.canRegister(); err != nil {
 			return err
 		}
 		h.c.SetRegistered()
 		h.s.Login(h.c)
 		h.welcome()
+		h.s.protectNick(h.c)
 		return nil
 	}
 	return nil
 }
 
+// When SASL is required, it replaces the connection password.
 func (h *DefaultHandler) canRegister() error {
+	if h.s.RequireSASL {
+		if h.c.User.Account == "" {
+			h.c.close("SASL authentication required")
+			return errors.New("not authenticated")
+		}
+		return nil
+	}
 	err := h.s.db.View(func(tx *bolt.Tx) error {
 		bpass := tx.Bucket(BucketConfig).Get(ConfigPass)
 		if bpass == nil {
@@ -446,6 +1112,37 @@ func (h *DefaultHandler) welcome() {
 	log.Printf("[%v] is %v", h.c.conn.RemoteAddr(), h.c.User.Nick)
 	h.c.Reply(RplWelcome, fmt.Sprintf("Welcome to the Internet Relay Chat Network %v", h.c.User.Nick)).
 		Reply(RplYourHost, fmt.Sprintf("Your host is %v running version %v", h.s.Origin(), Version)).
-		Reply(RplCreated, fmt.Sprintf("This server was started on %v", h.s.Started.Format(time.RFC1123))).
-		SendError(NewError(ErrNoMotd, "No MOTD set"))
+		Reply(RplCreated, fmt.Sprintf("This server was started on %v", h.s.Started.Format(time.RFC1123)))
+	h.c.SendMessage(Message{
+		Prefix: h.c.ServerName,
+		Target: h.c.User.Nick,
+		Cmd:    RplMyInfo,
+		Params: []string{
+			h.s.Origin(),
+			Version,
+			UserModeChars(),
+			ChanModeChars(),
+			ChanModeCharsWithParams(),
+		},
+		NoSpaces: true,
+	})
+	h.isupport()
+	h.s.Lusers(h.c)
+	h.motd()
+}
+
+// Tokens are sent in groups to keep each line within the maximum message
+// length.
+func (h *DefaultHandler) isupport() {
+	const maxTokens = 13
+	tokens := h.s.ISupport()
+	for len(tokens) > 0 {
+		n := len(tokens)
+		if n > maxTokens {
+			n = maxTokens
+		}
+		params := append(tokens[:n:n], "are supported by this server")
+		h.c.Reply(RplISupport, params...)
+		tokens = tokens[n:]
+	}
 }
@@ -0,0 +1,96 @@
+package irc
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ELIST extensions supported by the LIST command.
+// http://www.irc.org/tech_docs/005.html
+const ListExtensions = "MNTU"
+
+// ListMaxMasks is the most channel masks kept from a single LIST request.
+// Masks after this count are ignored and longer masks are truncated to
+// MaskMaxLen.
+const ListMaxMasks = 10
+
+// ListFilter holds the conditions given in a LIST request. A channel is
+// listed if it matches any of the masks, none of the excluded masks, and
+// all of the user count and topic age conditions.
+type ListFilter struct {
+	Masks       []string
+	NotMasks    []string
+	MinUsers    int
+	MaxUsers    int
+	TopicBefore time.Duration
+	TopicAfter  time.Duration
+}
+
+// ParseListFilter parses the comma separated list of channel masks and
+// conditions sent as the first parameter to LIST.
+func ParseListFilter(param string) ListFilter {
+	f := ListFilter{MinUsers: -1, MaxUsers: -1}
+	for _, item := range strings.Split(param, ",") {
+		switch {
+		case item == "":
+			continue
+		case strings.HasPrefix(item, ">"):
+			if n, err := strconv.Atoi(item[1:]); err == nil {
+				f.MinUsers = n
+			}
+		case strings.HasPrefix(item, "<"):
+			if n, err := strconv.Atoi(item[1:]); err == nil {
+				f.MaxUsers = n
+			}
+		case strings.HasPrefix(item, "T>"):
+			if n, err := strconv.Atoi(item[2:]); err == nil {
+				f.TopicBefore = time.Duration(n) * time.Minute
+			}
+		case strings.HasPrefix(item, "T<"):
+			if n, err := strconv.Atoi(item[2:]); err == nil {
+				f.TopicAfter = time.Duration(n) * time.Minute
+			}
+		case len(f.Masks)+len(f.NotMasks) >= ListMaxMasks:
+			continue
+		case strings.HasPrefix(item, "!"):
+			f.NotMasks = append(f.NotMasks, Truncate(item[1:], MaskMaxLen))
+		default:
+			f.Masks = append(f.Masks, Truncate(item, MaskMaxLen))
+		}
+	}
+	return f
+}
+
+// Match reports whether a channel with the given name, number of users and
+// topic age passes the filter. A zero topic age means no topic is set.
+func (f ListFilter) Match(name string, users int, topicAge time.Duration) bool {
+	if len(f.Masks) > 0 && !matchAny(f.Masks, name) {
+		return false
+	}
+	if matchAny(f.NotMasks, name) {
+		return false
+	}
+	if f.MinUsers >= 0 && users <= f.MinUsers {
+		return false
+	}
+	if f.MaxUsers >= 0 && users >= f.MaxUsers {
+		return false
+	}
+	if f.TopicBefore > 0 && (topicAge == 0 || topicAge <= f.TopicBefore) {
+		return false
+	}
+	if f.TopicAfter > 0 && (topicAge == 0 || topicAge >= f.TopicAfter) {
+		return false
+	}
+	return true
+}
+
+func matchAny(masks []string, text string) bool {
+	for _, mask := range masks {
+		if MatchMask(mask, text) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package irc
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var listFilterTests = []struct {
+	name     string
+	filter   string
+	chname   string
+	users    int
+	topicAge time.Duration
+	want     bool
+}{
+	{"empty", "", "#gotham", 1, 0, true},
+	{"mask", "#gotham", "#gotham", 1, 0, true},
+	{"mask wildcard", "#g*", "#gotham", 1, 0, true},
+	{"mask no match", "#batcave", "#gotham", 1, 0, false},
+	{"masks", "#batcave,#gotham", "#gotham", 1, 0, true},
+	{"not mask", "!#g*", "#gotham", 1, 0, false},
+	{"more users", ">1", "#gotham", 2, 0, true},
+	{"more users fail", ">1", "#gotham", 1, 0, false},
+	{"less users", "<3", "#gotham", 2, 0, true},
+	{"less users fail", "<3", "#gotham", 3, 0, false},
+	{"users range", ">1,<3", "#gotham", 2, 0, true},
+	{"topic older", "T>5", "#gotham", 1, 10 * time.Minute, true},
+	{"topic older fail", "T>5", "#gotham", 1, 1 * time.Minute, false},
+	{"topic newer", "T<5", "#gotham", 1, 1 * time.Minute, true},
+	{"topic newer fail", "T<5", "#gotham", 1, 10 * time.Minute, false},
+	{"no topic", "T<5", "#gotham", 1, 0, false},
+	{"too many masks", strings.Repeat("#batcave,", ListMaxMasks) + "#gotham", "#gotham", 1, 0, false},
+	{"long mask", "#gotham" + strings.Repeat("*", 200) + "x", "#gotham", 1, 0, true},
+}
+
+func TestListFilter(t *testing.T) {
+	for _, test := range listFilterTests {
+		t.Run(test.name, func(t *testing.T) {
+			f := ParseListFilter(test.filter)
+			have := f.Match(test.chname, test.users, test.topicAge)
+			if test.want != have {
+				t.Errorf("\n want: %v \n have: %v", test.want, have)
+			}
+		})
+	}
+}
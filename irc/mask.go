@@ -0,0 +1,101 @@
+package irc
+
+import "strings"
+
+// MaskMaxLen is the longest mask kept in a channel list. Longer masks are
+// truncated when they are added.
+const MaskMaxLen = 100
+
+// NormalizeMask expands a partial mask into the full nick!user@host form,
+// filling missing parts with wildcards.
+func NormalizeMask(mask string) string {
+	nick, user, host := "*", "*", "*"
+	rest := mask
+	if i := strings.Index(rest, "!"); i >= 0 {
+		nick = rest[:i]
+		rest = rest[i+1:]
+		if i := strings.Index(rest, "@"); i >= 0 {
+			user = rest[:i]
+			host = rest[i+1:]
+		} else {
+			user = rest
+		}
+	} else if i := strings.Index(rest, "@"); i >= 0 {
+		user = rest[:i]
+		host = rest[i+1:]
+	} else {
+		nick = rest
+	}
+	if nick == "" {
+		nick = "*"
+	}
+	if user == "" {
+		user = "*"
+	}
+	if host == "" {
+		host = "*"
+	}
+	return nick + "!" + user + "@" + host
+}
+
+// MatchMask reports whether the text matches the mask. The mask may
+// contain '*' to match any number of characters and '?' to match exactly
+// one. Matching is case insensitive.
+func MatchMask(mask string, text string) bool {
+	return MatchMaskFold(mask, text, strings.ToLower)
+}
+
+// MatchMaskFold is like MatchMask but compares the mask and text after
+// folding them with the case mapping of the server.
+func MatchMaskFold(mask string, text string, fold FoldFunc) bool {
+	return matchMask([]rune(fold(mask)), []rune(fold(text)))
+}
+
+// matchMask walks the mask and text together. On a mismatch it backtracks
+// to the last '*' and lets it swallow one more character, so the work is
+// bounded by the product of the two lengths.
+func matchMask(mask []rune, text []rune) bool {
+	m, t := 0, 0
+	star, next := -1, 0
+	for t < len(text) {
+		switch {
+		case m < len(mask) && mask[m] == '*':
+			star, next = m, t
+			m++
+		case m < len(mask) && (mask[m] == '?' || mask[m] == text[t]):
+			m++
+			t++
+		case star >= 0:
+			next++
+			m, t = star+1, next
+		default:
+			return false
+		}
+	}
+	for m < len(mask) && mask[m] == '*' {
+		m++
+	}
+	return m == len(mask)
+}
+
+// matchUser reports whether any of the masks match the user either by the
+// displayed host or the real host.
+func matchUser(masks []string, u *User, fold FoldFunc) bool {
+	real := *u
+	real.Host = u.RealHost
+	for _, mask := range masks {
+		if MatchMaskFold(mask, u.Origin(), fold) || MatchMaskFold(mask, real.Origin(), fold) {
+			return true
+		}
+	}
+	return false
+}
+
+func indexMask(masks []string, mask string, fold FoldFunc) int {
+	for i, m := range masks {
+		if fold(m) == fold(mask) {
+			return i
+		}
+	}
+	return -1
+}
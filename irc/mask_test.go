@@ -0,0 +1,94 @@
+package irc
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var normalizeMaskTests = []struct {
+	mask string
+	want string
+}{
+	{"joker", "joker!*@*"},
+	{"joker!jack", "joker!jack@*"},
+	{"jack@arkham", "*!jack@arkham"},
+	{"joker!jack@arkham", "joker!jack@arkham"},
+	{"!@", "*!*@*"},
+}
+
+func TestNormalizeMask(t *testing.T) {
+	for _, test := range normalizeMaskTests {
+		t.Run(test.mask, func(t *testing.T) {
+			have := NormalizeMask(test.mask)
+			if test.want != have {
+				t.Errorf("\n want: %v \n have: %v", test.want, have)
+			}
+		})
+	}
+}
+
+var matchMaskTests = []struct {
+	mask string
+	text string
+	want bool
+}{
+	{"*!*@*", "joker!~jack@arkham", true},
+	{"joker!*@*", "joker!~jack@arkham", true},
+	{"JOKER!*@*", "joker!~jack@arkham", true},
+	{"*!*@arkham", "joker!~jack@arkham", true},
+	{"*!*@*.arkham", "joker!~jack@arkham", false},
+	{"j?ker!*@*", "joker!~jack@arkham", true},
+	{"j?ker!*@*", "jker!~jack@arkham", false},
+	{"robin!*@*", "joker!~jack@arkham", false},
+	{"joker", "joker!~jack@arkham", false},
+	{"*jack*", "joker!~jack@arkham", true},
+	{"*a*a*b", "joker!~jack@arkham", false},
+	{"*!*@*m", "joker!~jack@arkham", true},
+	{"**", "", true},
+	{"?", "", false},
+}
+
+func TestMatchMask(t *testing.T) {
+	for _, test := range matchMaskTests {
+		t.Run(test.mask, func(t *testing.T) {
+			have := MatchMask(test.mask, test.text)
+			if test.want != have {
+				t.Errorf("\n want: %v \n have: %v", test.want, have)
+			}
+		})
+	}
+}
+
+func TestMatchMaskFold(t *testing.T) {
+	tests := []struct {
+		fold FoldFunc
+		want bool
+	}{
+		{FoldASCII, false},
+		{FoldRFC1459, true},
+	}
+	for _, test := range tests {
+		have := MatchMaskFold("Bat[man]!*@*", "bat{man}!~bruce@gotham", test.fold)
+		if test.want != have {
+			t.Errorf("\n want: %v \n have: %v", test.want, have)
+		}
+	}
+}
+
+func TestMatchMaskStars(t *testing.T) {
+	mask := strings.Repeat("*a", 20) + "*b"
+	text := strings.Repeat("a", 40) + "!~jack@arkham"
+	done := make(chan bool)
+	go func() {
+		done <- MatchMask(mask, text)
+	}()
+	select {
+	case have := <-done:
+		if have {
+			t.Errorf("\n want: %v \n have: %v", false, have)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("match took too long")
+	}
+}
@@ -1,12 +1,20 @@
 package irc
 
 import (
+	"sort"
 	"strings"
+	"unicode/utf8"
 )
 
 const MessageMaxLen = 512
 
+// TagsMaxLen is the number of bytes allowed for message tags, including
+// the leading @ and trailing space. It is not counted in MessageMaxLen.
+// https://ircv3.net/specs/extensions/message-tags
+const TagsMaxLen = 4096
+
 type Message struct {
+	Tags     map[string]string
 	Prefix   string
 	Cmd      string
 	Target   string
@@ -21,6 +29,11 @@ func NewMessage(cmd string, params ...string) Message {
 func DecodeMessage(line string) Message {
 	m := Message{}
 	m.Params = make([]string, 0)
+	if strings.HasPrefix(line, "@") {
+		tags, rest, _ := strings.Cut(line[1:], " ")
+		m.Tags = DecodeTags(tags)
+		line = rest
+	}
 	fields := strings.Split(line, " ")
 	if strings.HasPrefix(fields[0], ":") {
 		m.Prefix = fields[0][1:]
@@ -48,6 +61,9 @@ func DecodeMessage(line string) Message {
 
 func (m Message) Encode() string {
 	fields := make([]string, 0)
+	if len(m.Tags) > 0 {
+		fields = append(fields, "@"+EncodeTags(m.Tags))
+	}
 	if m.Prefix != "" {
 		fields = append(fields, ":"+m.Prefix)
 	}
@@ -72,7 +88,126 @@ func (m Message) String() string {
 	return m.Encode()
 }
 
+var tagEscapes = strings.NewReplacer(
+	";", "\\:",
+	" ", "\\s",
+	"\\", "\\\\",
+	"\r", "\\r",
+	"\n", "\\n",
+)
+
+// DecodeTags parses the tag section of a message, without the leading @.
+// Tags without a value have an empty value.
+func DecodeTags(text string) map[string]string {
+	tags := make(map[string]string)
+	for _, tag := range strings.Split(text, ";") {
+		if tag == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(tag, "=")
+		tags[key] = unescapeTagValue(value)
+	}
+	return tags
+}
+
+// EncodeTags formats tags for the tag section of a message, without the
+// leading @. Keys are sorted so that the result is always the same.
+func EncodeTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	items := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if value := tags[key]; value != "" {
+			items = append(items, key+"="+tagEscapes.Replace(value))
+		} else {
+			items = append(items, key)
+		}
+	}
+	return strings.Join(items, ";")
+}
+
+// An unknown escape is replaced by the character that follows the
+// backslash and a backslash at the end is dropped.
+func unescapeTagValue(value string) string {
+	var b strings.Builder
+	escaped := false
+	for _, ch := range value {
+		if !escaped {
+			if ch == '\\' {
+				escaped = true
+			} else {
+				b.WriteRune(ch)
+			}
+			continue
+		}
+		escaped = false
+		switch ch {
+		case ':':
+			b.WriteRune(';')
+		case 's':
+			b.WriteRune(' ')
+		case 'r':
+			b.WriteRune('\r')
+		case 'n':
+			b.WriteRune('\n')
+		default:
+			b.WriteRune(ch)
+		}
+	}
+	return b.String()
+}
+
+// ClientTags returns the client-only tags, those prefixed with a +, that
+// can be relayed to other clients.
+func ClientTags(tags map[string]string) map[string]string {
+	var client map[string]string
+	for key, value := range tags {
+		if strings.HasPrefix(key, "+") {
+			if client == nil {
+				client = make(map[string]string)
+			}
+			client[key] = value
+		}
+	}
+	return client
+}
+
+// SplitWords joins words with spaces into as few lines as possible where
+// no line is longer than max bytes. A single word longer than max is placed
+// on a line by itself.
+func SplitWords(words []string, max int) []string {
+	lines := make([]string, 0)
+	line := ""
+	for _, word := range words {
+		if line != "" && len(line)+1+len(word) > max {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	return append(lines, line)
+}
+
+// Truncate shortens text to at most max bytes without splitting a
+// multi-byte character.
+func Truncate(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	for max > 0 && !utf8.RuneStart(text[max]) {
+		max--
+	}
+	return text[:max]
+}
+
 type Command struct {
+	Tags   map[string]string
 	Name   string
 	Params []string
 }
@@ -98,3 +98,121 @@ func TestEncodeMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []string
+		max   int
+		lines []string
+	}{
+		{"empty", []string{}, 10, []string{""}},
+		{"one line", []string{"aa", "bb", "cc"}, 8, []string{"aa bb cc"}},
+		{"two lines", []string{"aa", "bb", "cc"}, 7, []string{"aa bb", "cc"}},
+		{"long word", []string{"aa", "bbbbbbbb", "cc"}, 5, []string{"aa", "bbbbbbbb", "cc"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			have := SplitWords(test.words, test.max)
+			if !reflect.DeepEqual(test.lines, have) {
+				t.Errorf("\n want: %q \n have: %q", test.lines, have)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		text string
+		max  int
+		want string
+	}{
+		{"abc", 5, "abc"},
+		{"abcdef", 3, "abc"},
+		{"añb", 2, "a"},
+		{"añb", 3, "añ"},
+		{"ñ", 1, ""},
+	}
+
+	for _, test := range tests {
+		have := Truncate(test.text, test.max)
+		if test.want != have {
+			t.Errorf("\n want: %q \n have: %q", test.want, have)
+		}
+	}
+}
+
+func TestDecodeMessageTags(t *testing.T) {
+	line := "@+draft/reply=abc;flag;time=2023-01-01T00:00:00Z :bob!~bob@localhost PRIVMSG #elsinore :Hi"
+	m := DecodeMessage(line)
+	tags := map[string]string{
+		"+draft/reply": "abc",
+		"time":         "2023-01-01T00:00:00Z",
+		"flag":         "",
+	}
+	if !reflect.DeepEqual(tags, m.Tags) {
+		t.Errorf("\n want: %v \n have: %v", tags, m.Tags)
+	}
+	if m.Prefix != "bob!~bob@localhost" || m.Cmd != "PRIVMSG" {
+		t.Errorf("unexpected prefix or command: %+v", m)
+	}
+	if have := m.Encode(); have != line {
+		t.Errorf("\n want: %v \n have: %v", line, have)
+	}
+}
+
+func TestTagEscapes(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		encoded string
+	}{
+		{"plain", "abc", "a=abc"},
+		{"semicolon", "a;b", `a=a\:b`},
+		{"space", "a b", `a=a\sb`},
+		{"backslash", `a\b`, `a=a\\b`},
+		{"newline", "a\r\nb", `a=a\r\nb`},
+		{"empty", "", "a"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			have := EncodeTags(map[string]string{"a": test.value})
+			if test.encoded != have {
+				t.Errorf("\n want: %v \n have: %v", test.encoded, have)
+			}
+			tags := DecodeTags(have)
+			if test.value != tags["a"] {
+				t.Errorf("\n want: %q \n have: %q", test.value, tags["a"])
+			}
+		})
+	}
+}
+
+func TestUnescapeTagValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{`a\b`, "ab"},
+		{`ab\`, "ab"},
+		{`a\\\:`, `a\;`},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			have := unescapeTagValue(test.value)
+			if test.want != have {
+				t.Errorf("\n want: %q \n have: %q", test.want, have)
+			}
+		})
+	}
+}
+
+func TestClientTags(t *testing.T) {
+	tags := map[string]string{"+typing": "active", "time": "now"}
+	want := map[string]string{"+typing": "active"}
+	have := ClientTags(tags)
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("\n want: %v \n have: %v", want, have)
+	}
+}
@@ -1,6 +1,9 @@
 package irc
 
-import "strings"
+import (
+	"sort"
+	"strings"
+)
 
 const (
 	ModeGrant  = "+"
@@ -40,6 +43,29 @@ const (
 	ChanModeVoice          = "v"
 )
 
+// Supported channel modes grouped by the types used in CHANMODES
+// http://www.irc.org/tech_docs/draft-brocklesby-irc-isupport-03.txt
+var (
+	// Modes that add or remove an address from a list
+	chanModesList = []string{ChanModeBan, ChanModeBanException, ChanModeInvitationMask}
+	// Modes that always have a parameter
+	chanModesParam = []string{ChanModeKeylock}
+	// Modes that only have a parameter when set
+	chanModesSetParam = []string{ChanModeLimit}
+	// Modes that never have a parameter
+	chanModesFlag = []string{ChanModeInviteOnly, ChanModeModerated, ChanModeNoExternalMsgs, ChanModeSecret, ChanModeTopicLock}
+	// Modes that grant a prefix to a member, in order of rank
+	chanModesPrefix = []string{ChanModeOper, ChanModeVoice}
+	chanPrefixes    = []string{"@", "+"}
+)
+
+const (
+	// Maximum number of modes with a parameter in a single MODE command
+	ChanModesMaxParams = 3
+	// Maximum number of entries in all list modes of a channel
+	ChanListMaxLen = 64
+)
+
 var chanModesWithArgs = map[string]bool{
 	"+b": true, "-b": true,
 	"+e": true, "-e": true,
@@ -64,6 +90,9 @@ const (
 	UserModeLocalOperator  = "O"
 )
 
+// Supported user modes
+var userModes = []string{UserModeAway, UserModeInvisible, UserModeGlobalOperator}
+
 var userModesWithArgs = map[string]bool{}
 
 func NewChanModes() *ChanModes {
@@ -84,6 +113,61 @@ func (c ChanModes) UserPrefix(id UserID) string {
 	return prefix
 }
 
+// ChanModeTypes returns the value for CHANMODES in RPL_ISUPPORT.
+func ChanModeTypes() string {
+	return strings.Join([]string{
+		strings.Join(chanModesList, ""),
+		strings.Join(chanModesParam, ""),
+		strings.Join(chanModesSetParam, ""),
+		strings.Join(chanModesFlag, ""),
+	}, ",")
+}
+
+// ChanPrefixes returns the value for PREFIX in RPL_ISUPPORT.
+func ChanPrefixes() string {
+	return "(" + strings.Join(chanModesPrefix, "") + ")" + strings.Join(chanPrefixes, "")
+}
+
+// ChanModeChars returns all supported channel modes for RPL_MYINFO.
+func ChanModeChars() string {
+	modes := make([]string, 0)
+	modes = append(modes, chanModesList...)
+	modes = append(modes, chanModesParam...)
+	modes = append(modes, chanModesSetParam...)
+	modes = append(modes, chanModesFlag...)
+	modes = append(modes, chanModesPrefix...)
+	return sortModes(modes)
+}
+
+// ChanModeCharsWithParams returns the channel modes that take a parameter
+// for RPL_MYINFO.
+func ChanModeCharsWithParams() string {
+	modes := make([]string, 0)
+	modes = append(modes, chanModesList...)
+	modes = append(modes, chanModesParam...)
+	modes = append(modes, chanModesSetParam...)
+	modes = append(modes, chanModesPrefix...)
+	return sortModes(modes)
+}
+
+// UserModeChars returns all supported user modes for RPL_MYINFO.
+func UserModeChars() string {
+	return sortModes(userModes)
+}
+
+// Sort case insensitive, upper case after lower case of the same letter.
+func sortModes(modes []string) string {
+	sorted := append([]string{}, modes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := strings.ToLower(sorted[i]), strings.ToLower(sorted[j])
+		if a != b {
+			return a < b
+		}
+		return sorted[i] > sorted[j]
+	})
+	return strings.Join(sorted, "")
+}
+
 type Mode struct {
 	Action string
 	Char   string
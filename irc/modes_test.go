@@ -67,3 +67,19 @@ func TestChanParseModes(t *testing.T) {
 		})
 	}
 }
+
+func TestChanModeTypes(t *testing.T) {
+	want := "beI,k,l,imnst"
+	have := ChanModeTypes()
+	if want != have {
+		t.Errorf("\n want: %v \n have: %v", want, have)
+	}
+}
+
+func TestChanPrefixes(t *testing.T) {
+	want := "(ov)@+"
+	have := ChanPrefixes()
+	if want != have {
+		t.Errorf("\n want: %v \n have: %v", want, have)
+	}
+}
@@ -9,9 +9,11 @@ import (
 )
 
 const (
-	NickMaxLen       = 40
-	NickExpireDelay  = 10 * time.Minute
-	NickReapInterval = 1 * time.Minute
+	NickMaxLen        = 40
+	NickExpireDelay   = 10 * time.Minute
+	NickReapInterval  = 1 * time.Minute
+	NickHistoryMaxLen = 10
+	NickHistoryMaxAge = 24 * time.Hour
 )
 
 type nickHistory struct {
@@ -21,24 +23,27 @@ type nickHistory struct {
 
 type Nicks struct {
 	active map[string]User
-	prev   map[string]nickHistory
+	prev   map[string][]nickHistory
 	mutex  sync.RWMutex
 	clk    clock.C
+	fold   FoldFunc
 	cancel context.CancelFunc
 }
 
 func NewNicks() *Nicks {
 	n := &Nicks{
 		active: make(map[string]User),
-		prev:   make(map[string]nickHistory),
+		prev:   make(map[string][]nickHistory),
 		clk:    clock.Real{},
+		fold:   FoldRFC1459,
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	n.cancel = cancel
 
 	go func() {
-		t := time.NewTimer(NickReapInterval)
+		t := time.NewTicker(NickReapInterval)
+		defer t.Stop()
 		for {
 			select {
 			case <-t.C:
@@ -55,47 +60,144 @@ func (n *Nicks) Close() {
 	n.cancel()
 }
 
+// Register reserves the nick for the user. If the user already holds a
+// nick, it is released without being recorded in the history.
 func (n *Nicks) Register(nick string, u *User) bool {
 	n.mutex.Lock()
 	defer n.mutex.Unlock()
 	if len(nick) > NickMaxLen {
 		nick = nick[:NickMaxLen]
 	}
+	if !ValidNick(nick) || !n.canRegister(nick, u) {
+		return false
+	}
+	if cur, exists := n.active[n.fold(u.Nick)]; exists && cur.ID == u.ID {
+		delete(n.active, n.fold(u.Nick))
+	}
+	u.Nick = nick
+	n.active[n.fold(nick)] = *u
+	return true
+}
+
+// Rename atomically moves the user to a new nick and records the previous
+// nick in the history.
+func (n *Nicks) Rename(u *User, nick string) bool {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if len(nick) > NickMaxLen {
+		nick = nick[:NickMaxLen]
+	}
+	if !ValidNick(nick) {
+		return false
+	}
+	// Only the display case is changing
+	if n.fold(nick) == n.fold(u.Nick) {
+		u.Nick = nick
+		n.active[n.fold(nick)] = *u
+		return true
+	}
 	if !n.canRegister(nick, u) {
 		return false
 	}
-	delete(n.prev, nick)
-	n.active[nick] = *u
+	delete(n.active, n.fold(u.Nick))
+	n.remember(u)
 	u.Nick = nick
+	n.active[n.fold(nick)] = *u
 	return true
 }
 
+// Unregister releases the nick held by the user and records the user in
+// the nick history.
 func (n *Nicks) Unregister(u *User) {
 	n.mutex.Lock()
 	defer n.mutex.Unlock()
-	delete(n.active, u.Nick)
+	if n.release(u) {
+		n.remember(u)
+	}
+}
+
+// Release frees the nick held by the user without recording it in the
+// history. It is used for clients that never finished registration.
+func (n *Nicks) Release(u *User) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.release(u)
+}
+
+func (n *Nicks) release(u *User) bool {
+	cur, exists := n.active[n.fold(u.Nick)]
+	if !exists || cur.ID != u.ID {
+		return false
+	}
+	delete(n.active, n.fold(u.Nick))
+	return true
 }
 
 func (n *Nicks) Get(name string) (User, bool) {
-	u, ok := n.active[name]
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	u, ok := n.active[n.fold(name)]
 	return u, ok
 }
 
+// history returns up to count previous users of the nick, most recent
+// first. All entries are returned if count is zero or less.
+func (n *Nicks) history(nick string, count int) []nickHistory {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	prev := n.prev[n.fold(nick)]
+	if count <= 0 || count > len(prev) {
+		count = len(prev)
+	}
+	return append([]nickHistory{}, prev[:count]...)
+}
+
+func (n *Nicks) remember(u *User) {
+	was := nickHistory{user: *u, seen: n.clk.Now()}
+	key := n.fold(u.Nick)
+	prev := append([]nickHistory{was}, n.prev[key]...)
+	if len(prev) > NickHistoryMaxLen {
+		prev = prev[:NickHistoryMaxLen]
+	}
+	n.prev[key] = prev
+}
+
+// ValidNick checks the nick against the grammar in RFC 2812:
+//
+//	nickname = ( letter / special ) *( letter / digit / special / "-" )
+//	special  = "[", "]", "\", "`", "_", "^", "{", "|", "}"
+func ValidNick(nick string) bool {
+	if nick == "" {
+		return false
+	}
+	for i, ch := range nick {
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z':
+		case ch >= '[' && ch <= '`', ch >= '{' && ch <= '}':
+		case i > 0 && (ch >= '0' && ch <= '9' || ch == '-'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 func (n *Nicks) canRegister(nick string, u *User) bool {
 	// Cannot register if the nick is already active
-	_, exists := n.active[nick]
+	_, exists := n.active[n.fold(nick)]
 	if exists {
 		return false
 	}
 
 	// Okay if the nick was not previously used
-	was, exists := n.prev[nick]
-	if !exists {
+	prev := n.prev[n.fold(nick)]
+	if len(prev) == 0 {
 		return true
 	}
+	was := prev[0]
 
 	// Okay if the nick has expired
-	if was.seen.Sub(n.clk.Now()) > NickExpireDelay {
+	if n.clk.Now().Sub(was.seen) >= NickExpireDelay {
 		return true
 	}
 
@@ -111,9 +213,17 @@ func (n *Nicks) reap() {
 	n.mutex.Lock()
 	defer n.mutex.Unlock()
 	now := n.clk.Now()
-	for nick, history := range n.prev {
-		if history.seen.Sub(now) > NickExpireDelay {
+	for nick, prev := range n.prev {
+		keep := prev[:0]
+		for _, was := range prev {
+			if now.Sub(was.seen) < NickHistoryMaxAge {
+				keep = append(keep, was)
+			}
+		}
+		if len(keep) == 0 {
 			delete(n.prev, nick)
+		} else {
+			n.prev[nick] = keep
 		}
 	}
 }
@@ -41,10 +41,10 @@ func TestNickCooldown(t *testing.T) {
 	u2 := &User{ID: 2}
 	nicks := NewNicks()
 	defer nicks.Close()
-	nicks.Register("Batman", &User{ID: 1})
+	nicks.Register("Batman", u1)
 	nicks.Unregister(u1)
 	if ok := nicks.Register("Batman", u2); ok {
-		t.Errorf("wnated to reject nick on cooldown")
+		t.Errorf("wanted to reject nick on cooldown")
 	}
 }
 
@@ -56,10 +56,166 @@ func TestNickCooldownExpire(t *testing.T) {
 	nicks.clk = mockClock
 
 	defer nicks.Close()
-	nicks.Register("Batman", &User{ID: 1})
+	nicks.Register("Batman", u1)
 	nicks.Unregister(u1)
 	mockClock.Add(NickExpireDelay)
-	if ok := nicks.Register("Batman", u2); ok {
-		t.Errorf("wnated to reuse nick")
+	if ok := nicks.Register("Batman", u2); !ok {
+		t.Errorf("wanted to reuse nick")
+	}
+}
+
+func TestNickHistory(t *testing.T) {
+	u1 := &User{ID: 1, Name: "bruce"}
+	u2 := &User{ID: 2, Name: "dick"}
+	nicks := NewNicks()
+	mockClock := &clock.Mock{}
+	nicks.clk = mockClock
+	defer nicks.Close()
+
+	nicks.Register("Batman", u1)
+	nicks.Unregister(u1)
+	mockClock.Add(NickExpireDelay)
+	nicks.Register("Batman", u2)
+	nicks.Unregister(u2)
+
+	prev := nicks.history("Batman", 0)
+	if len(prev) != 2 {
+		t.Fatalf("\n want: 2 \n have: %v", len(prev))
+	}
+	if prev[0].user.Name != "dick" || prev[1].user.Name != "bruce" {
+		t.Errorf("\n want: [dick bruce] \n have: [%v %v]", prev[0].user.Name, prev[1].user.Name)
+	}
+	prev = nicks.history("Batman", 1)
+	if len(prev) != 1 {
+		t.Errorf("\n want: 1 \n have: %v", len(prev))
+	}
+}
+
+func TestNickHistoryReap(t *testing.T) {
+	u1 := &User{ID: 1}
+	nicks := NewNicks()
+	mockClock := &clock.Mock{}
+	nicks.clk = mockClock
+	defer nicks.Close()
+
+	nicks.Register("Batman", u1)
+	nicks.Unregister(u1)
+	mockClock.Add(NickHistoryMaxAge)
+	nicks.reap()
+	if prev := nicks.history("Batman", 0); len(prev) != 0 {
+		t.Errorf("\n want: 0 \n have: %v", len(prev))
+	}
+}
+
+func TestReleaseNick(t *testing.T) {
+	u1 := &User{ID: 1}
+	u2 := &User{ID: 2}
+	nicks := NewNicks()
+	defer nicks.Close()
+
+	nicks.Register("Batman", u1)
+	nicks.Release(u1)
+	if prev := nicks.history("Batman", 0); len(prev) != 0 {
+		t.Errorf("\n want: 0 \n have: %v", len(prev))
+	}
+	if ok := nicks.Register("Batman", u2); !ok {
+		t.Errorf("wanted to reuse released nick")
+	}
+}
+
+func TestRenameNick(t *testing.T) {
+	u1 := &User{ID: 1}
+	nicks := NewNicks()
+	defer nicks.Close()
+	nicks.Register("Robin", u1)
+	if ok := nicks.Rename(u1, "Nightwing"); !ok {
+		t.Fatalf("wanted to rename nick")
+	}
+	if _, ok := nicks.Get("Robin"); ok {
+		t.Errorf("wanted old nick to be released")
+	}
+	if u, ok := nicks.Get("Nightwing"); !ok || u.ID != 1 {
+		t.Errorf("wanted new nick to be registered")
+	}
+	if prev := nicks.history("Robin", 0); len(prev) != 1 {
+		t.Errorf("wanted old nick in history")
+	}
+}
+
+func TestRenameNickInUse(t *testing.T) {
+	u1 := &User{ID: 1}
+	u2 := &User{ID: 2}
+	nicks := NewNicks()
+	defer nicks.Close()
+	nicks.Register("Batman", u1)
+	nicks.Register("Robin", u2)
+	if ok := nicks.Rename(u2, "Batman"); ok {
+		t.Fatalf("wanted nick collision")
+	}
+	if u2.Nick != "Robin" {
+		t.Errorf("\n want: Robin \n have: %v", u2.Nick)
+	}
+}
+
+var validNickTests = []struct {
+	nick string
+	want bool
+}{
+	{"Batman", true},
+	{"[Batman]", true},
+	{"bat-man", true},
+	{"Batman2", true},
+	{"`^_{|}\\", true},
+	{"", false},
+	{"2Batman", false},
+	{"-Batman", false},
+	{"#gotham", false},
+	{":Batman", false},
+	{"Bat man", false},
+	{"Bat,man", false},
+	{"Bat!man", false},
+}
+
+func TestValidNick(t *testing.T) {
+	for _, test := range validNickTests {
+		t.Run(test.nick, func(t *testing.T) {
+			have := ValidNick(test.nick)
+			if test.want != have {
+				t.Errorf("\n want: %v \n have: %v", test.want, have)
+			}
+		})
+	}
+}
+
+func TestRegisterNickInvalid(t *testing.T) {
+	nicks := NewNicks()
+	defer nicks.Close()
+	if ok := nicks.Register("#gotham", &User{}); ok {
+		t.Errorf("wanted to reject invalid nick")
+	}
+}
+
+func TestRegisterNickFolded(t *testing.T) {
+	nicks := NewNicks()
+	defer nicks.Close()
+	nicks.Register("Batman", &User{ID: 1})
+	if ok := nicks.Register("BATMAN", &User{ID: 2}); ok {
+		t.Errorf("wanted nick collision")
+	}
+	if u, ok := nicks.Get("batman"); !ok || u.Nick != "Batman" {
+		t.Errorf("wanted to find nick with display case")
+	}
+}
+
+func TestRenameNickCase(t *testing.T) {
+	u1 := &User{ID: 1}
+	nicks := NewNicks()
+	defer nicks.Close()
+	nicks.Register("batman", u1)
+	if ok := nicks.Rename(u1, "Batman"); !ok {
+		t.Fatalf("wanted to change case of nick")
+	}
+	if u, ok := nicks.Get("batman"); !ok || u.Nick != "Batman" {
+		t.Errorf("wanted nick with new display case")
 	}
 }
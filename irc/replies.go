@@ -1,28 +1,94 @@
 package irc
 
 const (
-	RplBanList       = "367"
-	RplChannelModeIs = "324"
-	RplCreated       = "003"
-	RplEndOfBanList  = "368"
-	RplEndOfMotd     = "376"
-	RplEndOfNames    = "366"
-	RplEndOfWho      = "315"
-	RplMotdStart     = "375"
-	RplMyInfo        = "004"
-	RplNameReply     = "353"
-	RplNoTopic       = "331"
-	RplTopic         = "332"
-	RplWelcome       = "001"
-	RplWhoReply      = "352"
-	RplYoureOper     = "381"
-	RplYourHost      = "002"
+	RplAdminEmail      = "259"
+	RplAdminLoc1       = "257"
+	RplAdminLoc2       = "258"
+	RplAdminMe         = "256"
+	RplAway            = "301"
+	RplBanList         = "367"
+	RplChannelModeIs   = "324"
+	RplCreated         = "003"
+	RplEndOfBanList    = "368"
+	RplEndOfExceptList = "349"
+	RplEndOfInfo       = "374"
+	RplEndOfInviteList = "347"
+	RplEndOfMotd       = "376"
+	RplEndOfNames      = "366"
+	RplEndOfStats      = "219"
+	RplEndOfWho        = "315"
+	RplEndOfWhois      = "318"
+	RplEndOfWhoWas     = "369"
+	RplExceptList      = "348"
+	RplGlobalUsers     = "266"
+	RplInfo            = "371"
+	RplInviteList      = "346"
+	RplInviting        = "341"
+	RplIson            = "303"
+	RplISupport        = "005"
+	RplList            = "322"
+	RplListEnd         = "323"
+	RplLocalUsers      = "265"
+	RplLoggedIn        = "900"
+	RplLoggedOut       = "901"
+	RplLuserChannels   = "254"
+	RplLuserClient     = "251"
+	RplLuserMe         = "255"
+	RplLuserOp         = "252"
+	RplLuserUnknown    = "253"
+	RplMotd            = "372"
+	RplMotdStart       = "375"
+	RplMyInfo          = "004"
+	RplNameReply       = "353"
+	RplNoTopic         = "331"
+	RplNowAway         = "306"
+	RplSaslMechs       = "908"
+	RplSaslSuccess     = "903"
+	RplStatsLinkInfo   = "211"
+	RplTime            = "391"
+	RplTopic           = "332"
+	RplUnAway          = "305"
+	RplUserHost        = "302"
+	RplVersion         = "351"
+	RplWelcome         = "001"
+	RplWhoisAccount    = "330"
+	RplWhoisChannels   = "319"
+	RplWhoisIdle       = "317"
+	RplWhoisOperator   = "313"
+	RplWhoisSecure     = "671"
+	RplWhoisServer     = "312"
+	RplWhoisUser       = "311"
+	RplWhoReply        = "352"
+	RplWhoWasUser      = "314"
+	RplYoureOper       = "381"
+	RplYourHost        = "002"
 )
 
 var RplText = map[string]string{
-	RplEndOfBanList: "End of Channel Ban List",
-	RplEndOfNames:   "End of NAMES list.",
-	RplEndOfWho:     "End of WHO list.",
-	RplNoTopic:      "No topic is set.",
-	RplYoureOper:    "You are now an IRC Operator",
+	RplAdminMe:         "Administrative info",
+	RplEndOfBanList:    "End of Channel Ban List",
+	RplEndOfExceptList: "End of Channel Exception List",
+	RplEndOfInfo:       "End of INFO list",
+	RplEndOfInviteList: "End of Channel Invite List",
+	RplEndOfMotd:       "End of MOTD command",
+	RplEndOfNames:      "End of NAMES list.",
+	RplEndOfStats:      "End of STATS report",
+	RplEndOfWho:        "End of WHO list.",
+	RplEndOfWhois:      "End of WHOIS list.",
+	RplEndOfWhoWas:     "End of WHOWAS",
+	RplListEnd:         "End of LIST",
+	RplLoggedOut:       "You are now logged out",
+	RplLuserChannels:   "channels formed",
+	RplLuserOp:         "operator(s) online",
+	RplLuserUnknown:    "unknown connection(s)",
+	RplNoTopic:         "No topic is set.",
+	RplNowAway:         "You have been marked as being away",
+	RplSaslMechs:       "are available SASL mechanisms",
+	RplSaslSuccess:     "SASL authentication successful",
+	RplUnAway:          "You are no longer marked as being away",
+	RplWhoisAccount:    "is logged in as",
+	RplWhoisIdle:       "seconds idle, signon time",
+	RplWhoisOperator:   "is an IRC operator",
+	RplWhoisSecure:     "is using a secure connection",
+	RplYoureOper:       "You are now an IRC Operator",
 }
@@ -0,0 +1,23 @@
+package irc
+
+// SASL mechanisms supported by AUTHENTICATE.
+// https://ircv3.net/specs/extensions/sasl-3.1
+const (
+	SaslExternal = "EXTERNAL"
+	SaslPlain    = "PLAIN"
+)
+
+// SaslMechs are advertised as the value of the sasl capability.
+var SaslMechs = []string{SaslPlain, SaslExternal}
+
+// Responses are sent in base64 chunks of SaslChunkLen bytes. A shorter
+// chunk, or a + after a full one, ends the response. The whole response
+// is limited to SaslMaxLen bytes.
+const (
+	SaslChunkLen = 400
+	SaslMaxLen   = 8192
+)
+
+// SaslMaxFails is the number of failed authentication attempts allowed
+// before the connection is closed.
+const SaslMaxFails = 3
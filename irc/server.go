@@ -4,11 +4,12 @@ import (
 	"bufio"
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -35,7 +36,15 @@ type Origin interface {
 	Origin() string
 }
 
-const queueMaxLen = 10
+var errExcessFlood = errors.New("excess flood")
+
+// closeTimeout limits how long a closing connection waits for the last
+// lines to be written.
+const closeTimeout = 5 * time.Second
+
+// DefaultSendQ is the number of bytes that can be waiting to be sent to a
+// client before it is disconnected.
+const DefaultSendQ = 64 * 1024
 
 type Server struct {
 	Name     string
@@ -44,9 +53,49 @@ type Server struct {
 	Insecure bool
 	DataFile string
 
+	// Network is the name of the IRC network advertised to clients
+	Network string
+
+	// MotdFile is read for the message of the day. A message set with
+	// SETMOTD is kept in the config bucket and is served instead.
+	MotdFile string
+
+	// Admin is the contact information sent in reply to ADMIN
+	Admin Admin
+
+	// CaseMapping selects how nicks and channel names are compared. The
+	// default is rfc1459.
+	CaseMapping string
+
+	// FloodBurst is the number of commands a client can send at once and
+	// FloodRate is the number of commands per second it can send after
+	// that. Commands sent faster are delayed and the client is disconnected
+	// once more than FloodBacklog are waiting. Operators are exempt.
+	FloodBacklog int
+	FloodBurst   int
+	FloodRate    float64
+
+	// SendQ is the number of bytes that can be waiting to be sent to a
+	// client. The client is disconnected if it is exceeded.
+	SendQ int
+
 	NewHandlerFunc       NewHandlerFunc
 	RegistrationDeadline time.Duration
 
+	// PingInterval is how long a connection can be silent before the
+	// server sends a PING. If nothing is received within PingTimeout of
+	// the PING, the client is disconnected.
+	PingInterval time.Duration
+	PingTimeout  time.Duration
+
+	// RequireSASL only allows clients that have logged in to an account
+	// with SASL to register. The connection password is not used.
+	RequireSASL bool
+
+	// NickGrace is how long a user has to log in to an account before
+	// losing a nick that is the name of the account.
+	NickGrace time.Duration
+
 	service  *Service
 	running  bool
 	wg       sync.WaitGroup
@@ -67,6 +116,30 @@ func (s *Server) ListenAndServe() error {
 	if int(s.RegistrationDeadline) == 0 {
 		s.RegistrationDeadline = 10 * time.Second
 	}
+	if s.FloodBacklog == 0 {
+		s.FloodBacklog = DefaultFloodBacklog
+	}
+	if s.FloodBurst == 0 {
+		s.FloodBurst = DefaultFloodBurst
+	}
+	if s.FloodRate == 0 {
+		s.FloodRate = DefaultFloodRate
+	}
+	if s.SendQ == 0 {
+		s.SendQ = DefaultSendQ
+	}
+	if s.PingInterval == 0 {
+		s.PingInterval = 2 * time.Minute
+	}
+	if s.PingTimeout == 0 {
+		s.PingTimeout = time.Minute
+	}
+	if s.NickGrace == 0 {
+		s.NickGrace = DefaultNickGrace
+	}
+	if s.CaseMapping == "" {
+		s.CaseMapping = DefaultCaseMapping
+	}
 
 	boltOpts := bolt.Options{Timeout: 5 * time.Second}
 	db, err := bolt.Open(s.DataFile, 0600, &boltOpts)
@@ -84,7 +157,25 @@ func (s *Server) ListenAndServe() error {
 		return fmt.Errorf("unable to initialize database %v: %v", s.DataFile, err)
 	}
 
-	s.service = newService(s.Name, db)
+	s.service, err = newService(s.Name, s.CaseMapping, db)
+	if err != nil {
+		return err
+	}
+	s.service.Network = s.Network
+	s.service.MotdFile = s.MotdFile
+	s.service.Admin = s.Admin
+	s.service.RequireSASL = s.RequireSASL
+	s.service.NickGrace = s.NickGrace
+	if !s.service.registerBeforeConnect() {
+		s.service.SetCap(CapAccountReg, "custom-account-name")
+	}
+	if err := s.service.restoreChans(); err != nil {
+		return fmt.Errorf("unable to restore channels: %v", err)
+	}
+	if s.Insecure {
+		// There are no client certificates without TLS
+		s.service.SetCap(CapSasl, SaslPlain)
+	}
 	s.quit = make(chan bool)
 
 	var tlsConfig tls.Config
@@ -99,6 +190,9 @@ func (s *Server) ListenAndServe() error {
 			}
 			tlsConfig = tls.Config{
 				Certificates: []tls.Certificate{cert},
+				// Client certificates are self-signed and are only used
+				// to log in to accounts with SASL EXTERNAL.
+				ClientAuth: tls.RequestClientCert,
 			}
 			return nil
 		})
@@ -160,6 +254,18 @@ func (s *Server) Prefix() string {
 	return s.Name
 }
 
+// SetCap makes a capability available, or changes its value, while the
+// server is running. Clients that enabled cap-notify are sent CAP NEW.
+func (s *Server) SetCap(name string, value string) {
+	s.service.SetCap(name, value)
+}
+
+// DelCap removes a capability while the server is running. Clients that
+// enabled cap-notify are sent CAP DEL.
+func (s *Server) DelCap(name string) {
+	s.service.DelCap(name)
+}
+
 func (s *Server) Quit() {
 	if s.running {
 		s.quit <- true
@@ -168,38 +274,137 @@ func (s *Server) Quit() {
 }
 
 func (s *Server) handle(conn net.Conn, debug bool) error {
+	s.service.connect()
+	defer s.service.disconnect()
+
 	cli := newClientUser(conn, s)
 	handler := s.NewHandlerFunc(s.service, cli)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	written := make(chan struct{})
 	go func() {
+		defer close(written)
 		defer cancel()
-		if err := writer(ctx, conn, cli.User, cli.sendq, debug); err != nil {
+		if err := writer(ctx, conn, cli, debug); err != nil {
 			log.Printf("[%v] %v", conn.RemoteAddr(), err)
 		}
 	}()
-	if err := reader(ctx, conn, cli.User, handler, debug); err != nil {
-		return err
+	go func() {
+		if pinger(ctx, cli, s.Name, s.PingInterval, s.PingTimeout) {
+			log.Printf("[%v] ping timeout", conn.RemoteAddr())
+			cli.close("Ping timeout")
+		}
+	}()
+	throttle := NewThrottle(s.FloodBurst, s.FloodRate)
+	delay := func(cmd string) time.Duration {
+		// Replies to PING and commands from operators are never held
+		// back. Commands sent before registration are throttled too so
+		// that AUTHENTICATE and REGISTER cannot be sent without limit.
+		if strings.EqualFold(cmd, PongCmd) || (cli.registered && s.service.isOper(cli.User.ID)) {
+			return 0
+		}
+		return throttle.Delay()
 	}
-	return nil
+	err := reader(ctx, conn, cli, handler, s.FloodBacklog, delay, debug)
+	reason := quitReason(cli, err)
+	s.service.Quit(cli, reason)
+
+	// Give the writer a moment to send the ERROR to the client
+	conn.SetWriteDeadline(time.Now().Add(closeTimeout))
+	<-written
+	return err
 }
 
-func reader(ctx context.Context, conn net.Conn, o Origin, handler Handler, debug bool) error {
-	lreader := &io.LimitedReader{R: conn, N: MessageMaxLen}
-	scanner := bufio.NewScanner(lreader)
+// quitReason describes why the connection was closed when the client did
+// not send a QUIT.
+func quitReason(cli *Client, err error) string {
+	if reason, closed := cli.closeReason(); closed {
+		return reason
+	}
+	var netErr net.Error
+	switch {
+	case err == bufio.ErrTooLong:
+		return "Input line too long"
+	case err == errExcessFlood:
+		return "Excess Flood"
+	case errors.As(err, &netErr) && netErr.Timeout() && !cli.registered:
+		return "Registration timeout"
+	}
+	return "Connection reset"
+}
+
+// reader passes each line received to the handler after waiting for the
+// time returned by delay. Lines that arrive while waiting are kept in a
+// backlog and the connection is dropped when it is full. Functions queued
+// on the client are run between lines.
+func reader(ctx context.Context, conn net.Conn, cli *Client, handler Handler, backlog int, delay func(string) time.Duration, debug bool) error {
+	lines := make(chan string, backlog)
+	flood := make(chan struct{})
+	var readErr error
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(conn)
+		scanner.Buffer(make([]byte, TagsMaxLen+MessageMaxLen), TagsMaxLen+MessageMaxLen)
+		scanner.Split(scanLines(TagsMaxLen + MessageMaxLen))
+		for scanner.Scan() {
+			line := scanner.Text()
+			cli.markReceived(len(line))
+			select {
+			case lines <- line:
+			default:
+				readErr = errExcessFlood
+				close(flood)
+				return
+			}
+		}
+		readErr = scanner.Err()
+	}()
+
 	for {
-		if ok := scanner.Scan(); !ok {
-			return scanner.Err()
+		var line string
+		select {
+		case l, ok := <-lines:
+			if !ok {
+				return readErr
+			}
+			line = l
+		case <-cli.taskWake:
+			for _, task := range cli.dequeueTasks() {
+				task()
+			}
+			continue
+		case <-flood:
+			return errExcessFlood
+		case <-ctx.Done():
+			return nil
 		}
-		lreader.N = MessageMaxLen
-		line := scanner.Text()
-		if debug {
-			log.Printf(" -> [%v] %v", o.Origin(), line)
+		tagsTooLong, textTooLong := tooLong(line)
+		if textTooLong {
+			return bufio.ErrTooLong
+		}
+		if tagsTooLong {
+			cli.SendError(NewError(ErrInputTooLong))
+			continue
 		}
 		m := DecodeMessage(line)
-		if err := handler.Handle(Command{Name: m.Cmd, Params: m.Params}); err != nil {
+		if d := delay(m.Cmd); d > 0 {
+			timer := time.NewTimer(d)
+			select {
+			case <-timer.C:
+			case <-flood:
+				timer.Stop()
+				return errExcessFlood
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			}
+		}
+		if debug {
+			log.Printf(" -> [%v] %v", cli.User.Origin(), line)
+		}
+		if err := handler.Handle(Command{Tags: m.Tags, Name: m.Cmd, Params: m.Params}); err != nil {
 			if err == Quit {
 				return nil
 			}
@@ -209,23 +414,97 @@ func reader(ctx context.Context, conn net.Conn, o Origin, handler Handler, debug
 	}
 }
 
-func writer(ctx context.Context, conn net.Conn, o Origin, sendq <-chan Message, debug bool) error {
+// scanLines splits lines like bufio.ScanLines but a line longer than max
+// bytes is returned cut to max bytes and the rest of it is discarded.
+func scanLines(max int) bufio.SplitFunc {
+	discard := false
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		switch {
+		case discard && advance > 0:
+			discard = false
+			return advance, nil, nil
+		case discard:
+			return len(data), nil, nil
+		case advance == 0 && len(data) >= max:
+			discard = true
+			return len(data), data[:max], nil
+		}
+		return advance, token, err
+	}
+}
+
+// tooLong checks the tags and the rest of the line, with the CR-LF that was
+// removed, against their separate limits.
+func tooLong(line string) (tags bool, text bool) {
+	tagsLen := 0
+	if strings.HasPrefix(line, "@") {
+		tagsLen = strings.Index(line, " ") + 1
+		if tagsLen == 0 {
+			tagsLen = len(line)
+		}
+	}
+	return tagsLen > TagsMaxLen, len(line)-tagsLen+2 > MessageMaxLen
+}
+
+// pinger sends a PING once nothing has been received from the client for
+// the interval. It returns true if nothing is received within the timeout
+// that follows.
+func pinger(ctx context.Context, cli *Client, origin string, interval time.Duration, timeout time.Duration) bool {
+	wait := func(d time.Duration) bool {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	for {
+		idle := time.Since(cli.received())
+		if idle < interval {
+			if !wait(interval - idle) {
+				return false
+			}
+			continue
+		}
+		sent := time.Now()
+		cli.Send(PingCmd, origin)
+		if !wait(timeout) {
+			return false
+		}
+		if cli.received().Before(sent) {
+			return true
+		}
+	}
+}
+
+// writer sends the lines in the send queue of the client. The connection
+// is closed once the client is closing and the queue is empty.
+func writer(ctx context.Context, conn net.Conn, cli *Client, debug bool) error {
 	w := bufio.NewWriter(conn)
 	for {
 		select {
-		case m := <-sendq:
-			line := m.Encode()
+		case <-cli.wake:
+		case <-ctx.Done():
+			return nil
+		}
+		lines, closing := cli.dequeue()
+		for _, line := range lines {
 			if debug {
-				log.Printf("<-  [%v] %v", o.Origin(), line)
+				log.Printf("<-  [%v] %v", cli.User.Origin(), line)
 			}
 			if _, err := w.WriteString(line + "\n"); err != nil {
 				return err
 			}
-			if err := w.Flush(); err != nil {
-				return err
-			}
-		case <-ctx.Done():
-			return nil
+			cli.markSent(len(line))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if closing {
+			return conn.Close()
 		}
 	}
 }
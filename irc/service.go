@@ -2,6 +2,10 @@ package irc
 
 import (
 	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -11,27 +15,58 @@ import (
 )
 
 type Service struct {
-	Name    string
-	Started time.Time
-	db      *bolt.DB
-	mutex   sync.RWMutex
-	chans   map[string]*Chan
-	nicks   *Nicks
-	modes   map[UserID]*UserModes
-	opers   map[UserID]bool
+	Name        string
+	Network     string
+	CaseMapping string
+	MotdFile    string
+	Admin       Admin
+	RequireSASL bool
+	NickGrace   time.Duration
+	Started     time.Time
+	db          *bolt.DB
+	fold        FoldFunc
+	mutex       sync.RWMutex
+	chans       map[string]*Chan
+	clients     map[UserID]*Client
+	nicks       *Nicks
+	modes       map[UserID]*UserModes
+	opers       map[UserID]bool
+	caps        map[string]string
+	conns       int
+	maxUsers    int
 }
 
-func newService(name string, db *bolt.DB) *Service {
+// Admin holds the contact details sent in reply to ADMIN.
+type Admin struct {
+	Location    string
+	Description string
+	Email       string
+}
+
+func newService(name string, caseMapping string, db *bolt.DB) (*Service, error) {
+	fold, err := CaseMappingFold(caseMapping)
+	if err != nil {
+		return nil, err
+	}
 	s := &Service{
-		Name:    name,
-		Started: time.Now(),
-		db:      db,
-		chans:   make(map[string]*Chan),
-		nicks:   NewNicks(),
-		modes:   make(map[UserID]*UserModes),
-		opers:   make(map[UserID]bool),
+		Name:        name,
+		CaseMapping: caseMapping,
+		Started:     time.Now(),
+		db:          db,
+		fold:        fold,
+		chans:       make(map[string]*Chan),
+		clients:     make(map[UserID]*Client),
+		nicks:       NewNicks(),
+		modes:       make(map[UserID]*UserModes),
+		opers:       make(map[UserID]bool),
+		caps:        make(map[string]string),
+		NickGrace:   DefaultNickGrace,
 	}
-	return s
+	for name, value := range DefaultCaps {
+		s.caps[name] = value
+	}
+	s.nicks.fold = fold
+	return s, nil
 }
 
 func (s *Service) Origin() string {
@@ -41,50 +76,373 @@ func (s *Service) Origin() string {
 func (s *Service) Chan(name string) (*Chan, error) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
-	ch, exists := s.chans[name]
+	ch, exists := s.chans[s.fold(name)]
 	if !exists {
 		return nil, NewError(ErrNoSuchChannel)
 	}
 	return ch, nil
 }
 
+// ISupport returns the tokens sent in RPL_ISUPPORT.
+func (s *Service) ISupport() []string {
+	tokens := []string{
+		"CASEMAPPING=" + s.CaseMapping,
+		"CHANMODES=" + ChanModeTypes(),
+		"CHANNELLEN=" + strconv.Itoa(ChanMaxLen),
+		"CHANTYPES=" + ChanTypes,
+		"ELIST=" + ListExtensions,
+		"EXCEPTS=" + ChanModeBanException,
+		"INVEX=" + ChanModeInvitationMask,
+		"MAXLIST=" + strings.Join(chanModesList, "") + ":" + strconv.Itoa(ChanListMaxLen),
+		"MODES=" + strconv.Itoa(ChanModesMaxParams),
+		"NICKLEN=" + strconv.Itoa(NickMaxLen),
+		"PREFIX=" + ChanPrefixes(),
+		"TOPICLEN=" + strconv.Itoa(TopicMaxLen),
+	}
+	if s.Network != "" {
+		tokens = append(tokens, "NETWORK="+s.Network)
+	}
+	return tokens
+}
+
 func (s *Service) Login(c *Client) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	s.modes[c.User.ID] = &UserModes{}
+	s.clients[c.User.ID] = c
+	if len(s.clients) > s.maxUsers {
+		s.maxUsers = len(s.clients)
+	}
+}
+
+// Connections that have not yet registered are counted as unknown in
+// LUSERS.
+func (s *Service) connect() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.conns++
+}
+
+func (s *Service) disconnect() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.conns--
+}
+
+// Caps returns the capabilities that clients can request and their values.
+func (s *Service) Caps() map[string]string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	caps := make(map[string]string, len(s.caps))
+	for name, value := range s.caps {
+		caps[name] = value
+	}
+	return caps
+}
+
+// SetCap makes a capability available, or changes its value, and tells
+// clients that enabled cap-notify with CAP NEW.
+func (s *Service) SetCap(name string, value string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.caps[name] = value
+	for _, c := range s.clients {
+		if version, notify := c.capNotify(); notify {
+			list := capList(map[string]string{name: value}, version)
+			c.Send(CapCmd, c.User.Nick, CapNewCmd, list[0])
+		}
+	}
+}
+
+// DelCap removes a capability, disables it for every client, and tells
+// clients that enabled cap-notify with CAP DEL.
+func (s *Service) DelCap(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, exists := s.caps[name]; !exists {
+		return
+	}
+	delete(s.caps, name)
+	for _, c := range s.clients {
+		_, notify := c.capNotify()
+		c.setCap(name, false)
+		if notify {
+			c.Send(CapCmd, c.User.Nick, CapDelCmd, name)
+		}
+	}
+}
+
+func (s *Service) hasCap(name string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	_, exists := s.caps[name]
+	return exists
 }
 
 // ==== Commands
 
+// Away marks the client as away with the given message, or as back when the
+// message is empty. Channel peers that enabled away-notify are told of the
+// change.
+func (s *Service) Away(src *Client, msg string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.modes[src.User.ID].Away = msg != ""
+	src.User.AwayMsg = msg
+	if msg == "" {
+		src.Reply(RplUnAway)
+	} else {
+		src.Reply(RplNowAway)
+	}
+
+	notify := make(map[UserID]*Client)
+	for _, ch := range src.chans {
+		for _, m := range ch.Members() {
+			if m != src && m.HasCap(CapAwayNotify) {
+				notify[m.User.ID] = m
+			}
+		}
+	}
+	for _, cli := range notify {
+		if msg == "" {
+			cli.Relay(src.User, AwayCmd)
+		} else {
+			cli.Relay(src.User, AwayCmd, msg)
+		}
+	}
+}
+
+func (s *Service) Invite(src *Client, nick string, name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	target, err := s.client(nick)
+	if err != nil {
+		return err
+	}
+	// Inviting to a channel that does not exist is allowed
+	if ch, exists := s.chans[s.fold(name)]; exists {
+		if err := ch.Invite(src, target); err != nil {
+			return err
+		}
+		target.invited[s.fold(name)] = ch
+	}
+	src.Reply(RplInviting, target.User.Nick, name)
+	if s.modes[target.User.ID].Away {
+		src.Reply(RplAway, target.User.Nick, target.User.AwayMsg)
+	}
+	target.Relay(src.User, InviteCmd, target.User.Nick, name)
+	return nil
+}
+
+// Ison replies with the nicks in the list that are currently in use.
+func (s *Service) Ison(src *Client, nicks []string) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	online := make([]string, 0)
+	for _, nick := range nicks {
+		if target, err := s.client(nick); err == nil {
+			online = append(online, target.User.Nick)
+		}
+	}
+	src.ReplyWords(RplIson, nil, online)
+}
+
 func (s *Service) Join(c *Client, name string, key string) (*Chan, error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	ch, exists := s.chans[name]
+	ch, exists := s.chans[s.fold(name)]
 	if !exists {
+		if err := ValidChanName(name); err != nil {
+			return nil, err
+		}
 		ch = NewChan(name, s.nicks)
-		s.chans[name] = ch
+		s.chans[s.fold(name)] = ch
 	}
 	err := ch.Join(c, key)
 	if err != nil {
 		return ch, err
 	}
-	c.chans[name] = ch
+	c.chans[s.fold(name)] = ch
+	delete(c.invited, s.fold(name))
+	if s.modes[c.User.ID].Away {
+		for _, m := range ch.Members() {
+			if m != c && m.HasCap(CapAwayNotify) {
+				m.Relay(c.User, AwayCmd, c.User.AwayMsg)
+			}
+		}
+	}
 	return ch, nil
 }
 
+func (s *Service) Kick(src *Client, name string, nick string, reason string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ch, exists := s.chans[s.fold(name)]
+	if !exists {
+		return NewError(ErrNoSuchChannel, name)
+	}
+	target, err := ch.Kick(src, nick, reason)
+	if err != nil {
+		return err
+	}
+	delete(target.chans, s.fold(name))
+	s.removeIfEmpty(ch)
+	return nil
+}
+
+// List replies with the channels that pass the filter. The channels are
+// copied first so that the masks are not matched while the service lock
+// is held.
+func (s *Service) List(src *Client, filter ListFilter) {
+	s.mutex.RLock()
+	keys := make([]string, 0, len(s.chans))
+	for key := range s.chans {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	chans := make([]*Chan, len(keys))
+	for i, key := range keys {
+		chans[i] = s.chans[key]
+	}
+	s.mutex.RUnlock()
+	for _, ch := range chans {
+		users, topic, age, visible := ch.Listing(src)
+		if !visible || !filter.Match(ch.Name(), users, age) {
+			continue
+		}
+		src.Reply(RplList, ch.Name(), strconv.Itoa(users), topic)
+	}
+	src.Reply(RplListEnd)
+}
+
+func (s *Service) Lusers(src *Client) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	users := len(s.clients)
+	src.Reply(RplLuserClient, fmt.Sprintf("There are %v users and 0 services on 1 servers", users))
+	if opers := len(s.opers); opers > 0 {
+		src.Reply(RplLuserOp, strconv.Itoa(opers))
+	}
+	if unknown := s.conns - users; unknown > 0 {
+		src.Reply(RplLuserUnknown, strconv.Itoa(unknown))
+	}
+	if chans := len(s.chans); chans > 0 {
+		src.Reply(RplLuserChannels, strconv.Itoa(chans))
+	}
+	src.Reply(RplLuserMe, fmt.Sprintf("I have %v users, 0 services and 0 servers", users))
+
+	cur, max := strconv.Itoa(users), strconv.Itoa(s.maxUsers)
+	src.Reply(RplLocalUsers, cur, max, fmt.Sprintf("Current local users %v, max %v", cur, max))
+	src.Reply(RplGlobalUsers, cur, max, fmt.Sprintf("Current global users %v, max %v", cur, max))
+}
+
 func (s *Service) Mode(src *Client) *UserModeCmds {
 	return newUserModeCmds(s, src)
 }
 
+// Motd returns the lines of the message of the day. It is read again on
+// each call so that changes are seen without a restart.
+func (s *Service) Motd() ([]string, error) {
+	var text []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		// Values are only valid during the transaction
+		text = append([]byte(nil), tx.Bucket(BucketConfig).Get(ConfigMotd)...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if text == nil && s.MotdFile != "" {
+		text, err = os.ReadFile(s.MotdFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return motdLines(string(text)), nil
+}
+
+// SetMotd replaces the message of the day. Only operators may change it.
+// The text is kept in the config bucket and is served instead of MotdFile,
+// which is never written. An empty text removes it. Lines are separated by
+// \n escapes since the text is sent as a single parameter, and \\ is a
+// backslash.
+func (s *Service) SetMotd(src *Client, text string) error {
+	s.mutex.RLock()
+	oper := s.opers[src.User.ID]
+	s.mutex.RUnlock()
+	if !oper {
+		return NewError(ErrNoPrivileges)
+	}
+	text = unescapeMotd(text)
+	return s.db.Update(func(tx *bolt.Tx) error {
+		config := tx.Bucket(BucketConfig)
+		if text == "" {
+			return config.Delete(ConfigMotd)
+		}
+		return config.Put(ConfigMotd, []byte(text))
+	})
+}
+
+// Nick sets the nick of the client. Once registered, the change is relayed
+// to the client and everyone who shares a channel with it.
 func (s *Service) Nick(c *Client, nick string) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	if ok := s.nicks.Register(nick, c.User); !ok {
+	if !ValidNick(nick) {
+		return NewError(ErrErroneusNickname, nick)
+	}
+	if !c.registered {
+		if ok := s.nicks.Register(nick, c.User); !ok {
+			return NewError(ErrNickNameInUse, nick)
+		}
+		return nil
+	}
+
+	if err := s.rename(c, nick); err != nil {
+		return err
+	}
+	s.watchNick(c)
+	return nil
+}
+
+// rename changes the nick of a registered client. The service mutex must be
+// held by the caller.
+func (s *Service) rename(c *Client, nick string) error {
+	prev := *c.User
+	if ok := s.nicks.Rename(c.User, nick); !ok {
 		return NewError(ErrNickNameInUse, nick)
 	}
+	if prev.Nick == c.User.Nick {
+		return nil
+	}
+	notify := map[UserID]*Client{c.User.ID: c}
+	for _, ch := range c.chans {
+		for _, m := range ch.Members() {
+			notify[m.User.ID] = m
+		}
+	}
+	for _, cli := range notify {
+		cli.Relay(prev, NickCmd, c.User.Nick)
+	}
 	return nil
 }
 
+// Notice delivers text like PrivMsg but, as required by RFC 2812, never
+// generates an error in response.
+func (s *Service) Notice(src *Client, dest string, text string, tags map[string]string) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if HasChanPrefix(dest) {
+		if ch, ok := s.chans[s.fold(dest)]; ok {
+			ch.Notice(src, text, tags)
+		}
+		return
+	}
+	if target, err := s.client(dest); err == nil {
+		target.RelayTags(src.User, tags, NoticeCmd, target.User.Nick, text)
+	}
+}
+
 func (s *Service) Oper(c *Client, nick string, plaintext string) error {
 	err := s.db.View(func(tx *bolt.Tx) error {
 		oper := tx.Bucket(BucketOpers).Bucket([]byte(nick))
@@ -111,7 +469,7 @@ func (s *Service) Oper(c *Client, nick string, plaintext string) error {
 func (s *Service) Part(c *Client, name string, reason string) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	ch, exists := s.chans[name]
+	ch, exists := s.chans[s.fold(name)]
 	if !exists {
 		return NewError(ErrNoSuchChannel, name)
 	}
@@ -119,27 +477,49 @@ func (s *Service) Part(c *Client, name string, reason string) error {
 	if err != nil {
 		return err
 	}
-	delete(c.chans, name)
+	delete(c.chans, s.fold(name))
+	s.removeIfEmpty(ch)
 	return nil
 }
 
-func (s *Service) PrivMsg(src *Client, dest string, text string) error {
+func (s *Service) PrivMsg(src *Client, dest string, text string, tags map[string]string) error {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
-	if strings.HasPrefix(dest, "#") {
-		ch, ok := s.chans[dest]
+	if HasChanPrefix(dest) {
+		ch, ok := s.chans[s.fold(dest)]
 		if !ok {
 			return NewError(ErrNoSuchNick, dest)
 		}
-		return ch.PrivMsg(src, text)
+		return ch.PrivMsg(src, text, tags)
+	}
+	target, err := s.client(dest)
+	if err != nil {
+		return err
+	}
+	target.RelayTags(src.User, tags, PrivMsgCmd, target.User.Nick, text)
+	if s.modes[target.User.ID].Away {
+		src.Reply(RplAway, target.User.Nick, target.User.AwayMsg)
 	}
 	return nil
 }
 
+// Quit removes the client from the service and relays the reason to its
+// channel peers. Only the first call for a client has any effect.
 func (s *Service) Quit(src *Client, reason string) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
+	if src.quit {
+		return
+	}
+	src.quit = true
+	s.stopNickTimer(src)
 	notify := make(map[UserID]*Client)
+	for key, ch := range src.invited {
+		if _, member := src.chans[key]; !member {
+			// Drop the pending invitation
+			ch.Quit(src)
+		}
+	}
 	for _, ch := range src.chans {
 		members := ch.Members()
 		for _, m := range members {
@@ -149,16 +529,221 @@ func (s *Service) Quit(src *Client, reason string) {
 			notify[m.User.ID] = m
 		}
 		ch.Quit(src)
+		s.removeIfEmpty(ch)
 	}
 	for _, cli := range notify {
 		cli.Relay(src.User, QuitCmd, reason)
 	}
 	src.Quit()
-	s.nicks.Unregister(src.User)
+	src.close(reason)
+	if src.registered {
+		s.nicks.Unregister(src.User)
+	} else {
+		s.nicks.Release(src.User)
+	}
+	delete(s.clients, src.User.ID)
 	delete(s.modes, src.User.ID)
 	delete(s.opers, src.User.ID)
 }
 
+// Stats replies to a STATS query from an operator. Only the l query, which
+// shows the send queue and traffic for each connection, is supported.
+func (s *Service) Stats(src *Client, query string) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if !s.opers[src.User.ID] {
+		return NewError(ErrNoPrivileges)
+	}
+	switch query {
+	case "l", "L":
+		clients := make([]*Client, 0, len(s.clients))
+		for _, c := range s.clients {
+			clients = append(clients, c)
+		}
+		sort.Slice(clients, func(i, j int) bool {
+			return clients[i].User.Nick < clients[j].User.Nick
+		})
+		for _, c := range clients {
+			u := c.User
+			stats := c.linkStats()
+			src.Reply(RplStatsLinkInfo,
+				fmt.Sprintf("%v[~%v@%v]", u.Nick, u.Name, u.RealHost),
+				strconv.Itoa(c.SendQ()),
+				strconv.Itoa(stats.SentMsgs),
+				strconv.Itoa(stats.SentBytes/1024),
+				strconv.Itoa(stats.RecvMsgs),
+				strconv.Itoa(stats.RecvBytes/1024),
+				strconv.FormatInt(int64(time.Since(c.signon).Seconds()), 10),
+			)
+		}
+	}
+	src.Reply(RplEndOfStats, query)
+	return nil
+}
+
+// TagMsg sends tags without a message to a channel or user. Only clients
+// that negotiated message-tags receive it.
+func (s *Service) TagMsg(src *Client, dest string, tags map[string]string) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if HasChanPrefix(dest) {
+		ch, ok := s.chans[s.fold(dest)]
+		if !ok {
+			return NewError(ErrNoSuchNick, dest)
+		}
+		return ch.TagMsg(src, tags)
+	}
+	target, err := s.client(dest)
+	if err != nil {
+		return err
+	}
+	target.RelayTags(src.User, tags, TagMsgCmd, target.User.Nick)
+	return nil
+}
+
+// UserHost replies with the host of each nick in the list that is in use.
+// Operators are flagged with a * and away users with a - instead of a +.
+func (s *Service) UserHost(src *Client, nicks []string) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	replies := make([]string, 0)
+	for _, nick := range nicks {
+		target, err := s.client(nick)
+		if err != nil {
+			continue
+		}
+		u := target.User
+		oper := ""
+		if s.opers[u.ID] {
+			oper = "*"
+		}
+		away := "+"
+		if s.modes[u.ID].Away {
+			away = "-"
+		}
+		replies = append(replies, fmt.Sprintf("%v%v=%v~%v@%v", u.Nick, oper, away, u.Name, u.Host))
+	}
+	src.ReplyWords(RplUserHost, nil, replies)
+}
+
+func (s *Service) Whois(src *Client, nick string) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	target, err := s.client(nick)
+	if err != nil {
+		return err
+	}
+	u := target.User
+	src.Reply(RplWhoisUser, u.Nick, "~"+u.Name, u.Host, "*", u.FullName)
+
+	chans := make([]string, 0, len(target.chans))
+	for _, ch := range target.chans {
+		prefix, _ := ch.Prefix(u.ID)
+		if !ch.Visible(src) {
+			continue
+		}
+		chans = append(chans, prefix+ch.Name())
+	}
+	if len(chans) > 0 {
+		sort.Strings(chans)
+		src.Reply(RplWhoisChannels, u.Nick, strings.Join(chans, " "))
+	}
+
+	src.Reply(RplWhoisServer, u.Nick, s.Name, Version)
+	if s.modes[u.ID].Away {
+		src.Reply(RplAway, u.Nick, u.AwayMsg)
+	}
+	if s.opers[u.ID] {
+		src.Reply(RplWhoisOperator, u.Nick)
+	}
+	if target.secure {
+		src.Reply(RplWhoisSecure, u.Nick)
+	}
+	if u.Account != "" {
+		src.Reply(RplWhoisAccount, u.Nick, u.Account)
+	}
+	idle := strconv.FormatInt(int64(target.Idle().Seconds()), 10)
+	signon := strconv.FormatInt(target.signon.Unix(), 10)
+	src.Reply(RplWhoisIdle, u.Nick, idle, signon)
+	return nil
+}
+
+func (s *Service) WhoWas(src *Client, nick string, count int) error {
+	prev := s.nicks.history(nick, count)
+	if len(prev) == 0 {
+		return NewError(ErrWasNoSuchNick, nick)
+	}
+	for _, was := range prev {
+		u := was.user
+		src.Reply(RplWhoWasUser, u.Nick, "~"+u.Name, u.Host, "*", u.FullName)
+		src.Reply(RplWhoisServer, u.Nick, s.Name, was.seen.Format(time.RFC1123))
+	}
+	return nil
+}
+
+// motdLines splits the message of the day into the lines sent with
+// RPL_MOTD. Trailing blank lines are dropped.
+func motdLines(text string) []string {
+	text = strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+func unescapeMotd(text string) string {
+	var b strings.Builder
+	escaped := false
+	for _, r := range text {
+		switch {
+		case escaped && r == 'n':
+			b.WriteRune('\n')
+		case escaped && r == '\\':
+			b.WriteRune(r)
+		case escaped:
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r == '\\':
+			escaped = true
+			continue
+		default:
+			b.WriteRune(r)
+		}
+		escaped = false
+	}
+	if escaped {
+		b.WriteRune('\\')
+	}
+	return b.String()
+}
+
+func (s *Service) isOper(id UserID) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.opers[id]
+}
+
+func (s *Service) away(id UserID) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	modes, ok := s.modes[id]
+	return ok && modes.Away
+}
+
+// client returns the registered client that currently owns the nick. The
+// service mutex must be held by the caller.
+func (s *Service) client(nick string) (*Client, error) {
+	u, exists := s.nicks.Get(nick)
+	if !exists {
+		return nil, NewError(ErrNoSuchNick, nick)
+	}
+	c, exists := s.clients[u.ID]
+	if !exists {
+		return nil, NewError(ErrNoSuchNick, nick)
+	}
+	return c, nil
+}
+
 // ===== User Modes
 
 type UserModeCmds struct {
@@ -0,0 +1,135 @@
+package irc
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMotdLines(t *testing.T) {
+	tests := []struct {
+		text  string
+		lines []string
+	}{
+		{"", nil},
+		{"\n\n", nil},
+		{"Welcome", []string{"Welcome"}},
+		{"Welcome\nto Gotham\n", []string{"Welcome", "to Gotham"}},
+		{"Welcome\r\n\r\nto Gotham\r\n", []string{"Welcome", "", "to Gotham"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			have := motdLines(test.text)
+			if !reflect.DeepEqual(test.lines, have) {
+				t.Fatalf("\n want: %q \n have: %q", test.lines, have)
+			}
+		})
+	}
+}
+
+func TestUnescapeMotd(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Welcome", "Welcome"},
+		{`Welcome\nto Gotham`, "Welcome\nto Gotham"},
+		{`C:\\batcave`, `C:\batcave`},
+		{`C:\batcave`, `C:\batcave`},
+		{`Gotham\`, `Gotham\`},
+	}
+	for _, test := range tests {
+		have := unescapeMotd(test.text)
+		if test.want != have {
+			t.Errorf("\n want: %q \n have: %q", test.want, have)
+		}
+	}
+}
+
+func TestSetMotdMultiline(t *testing.T) {
+	s := newTestService(t)
+	c := &Client{User: &User{ID: 1}}
+	s.opers[c.User.ID] = true
+
+	if err := s.SetMotd(c, `Welcome\nto Gotham`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	have, err := s.Motd()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"Welcome", "to Gotham"}
+	if !reflect.DeepEqual(want, have) {
+		t.Fatalf("\n want: %q \n have: %q", want, have)
+	}
+}
+
+func TestSetMotdFile(t *testing.T) {
+	s := newTestService(t)
+	s.MotdFile = filepath.Join(t.TempDir(), "motd.txt")
+	if err := os.WriteFile(s.MotdFile, []byte("Welcome to Gotham"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{User: &User{ID: 1}}
+	s.opers[c.User.ID] = true
+
+	if err := s.SetMotd(c, "Welcome to Arkham"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	have, err := s.Motd()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"Welcome to Arkham"}
+	if !reflect.DeepEqual(want, have) {
+		t.Fatalf("\n want: %q \n have: %q", want, have)
+	}
+	file, err := os.ReadFile(s.MotdFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(file) != "Welcome to Gotham" {
+		t.Fatalf("file was changed: %q", file)
+	}
+
+	// Removing the message serves the file again
+	if err := s.SetMotd(c, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	have, err = s.Motd()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want = []string{"Welcome to Gotham"}
+	if !reflect.DeepEqual(want, have) {
+		t.Fatalf("\n want: %q \n have: %q", want, have)
+	}
+}
+
+func TestServiceQuitClearsInvite(t *testing.T) {
+	s := newTestService(t)
+	batman := newTestClient(1000)
+	batman.User.ID = 1
+	robin := newTestClient(1000)
+	robin.User = &User{ID: 2, Nick: "Robin"}
+	s.Login(batman)
+	s.Login(robin)
+	s.nicks.Register("Robin", robin.User)
+
+	ch, err := s.Join(batman, "#gotham", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Invite(batman, "Robin", "#gotham"); err != nil {
+		t.Fatal(err)
+	}
+	if _, invited := robin.invited["#gotham"]; !invited {
+		t.Fatalf("invite not recorded: %v", robin.invited)
+	}
+	s.Quit(robin, "")
+	if len(ch.invites) != 0 {
+		t.Errorf("invites not cleared: %v", ch.invites)
+	}
+}
@@ -15,6 +15,10 @@ type User struct {
 	Host     string
 	RealHost string
 	FullName string
+	AwayMsg  string
+
+	// Account is the name of the account the user is logged in to
+	Account string
 }
 
 func newUser(host string, realHost string) *User {